@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// fileSuffix is the suffix of the variables holding the path of a file from
+// which the variable without the suffix is filled, e.g. OFGA_TOKEN_FILE for
+// OFGA_TOKEN.
+const fileSuffix = "_FILE"
+
+// loadData returns the template data. It holds the variables read from the
+// given env files, later files taking precedence, overridden by the process
+// environment. Variables filled from files are resolved by resolveFileVars
+// once the templates are parsed.
+func loadData(envFiles []string) (map[string]string, error) {
+	data := make(map[string]string)
+
+	for _, path := range envFiles {
+		vars, err := readEnvFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			data[k] = v
+		}
+	}
+
+	for k, v := range envToMap() {
+		data[k] = v
+	}
+
+	return data, nil
+}
+
+// readEnvFile reads the KEY=VALUE lines of the file at path. Blank lines and
+// lines starting with "#" are ignored, an "export " prefix is allowed and
+// values may be surrounded by single or double quotes.
+func readEnvFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read env file: %v", err)
+	}
+
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+
+		vars[key] = unquote(strings.TrimSpace(value))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("couldn't read env file: %v", err)
+	}
+
+	return vars, nil
+}
+
+// unquote removes matching single or double quotes surrounding value.
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// resolveFileVars fills every variable FOO referenced by the templates for
+// which FOO_FILE is set with the content of the named file, so that secrets
+// mounted as files need not be passed as environment variables. It is an error
+// for both FOO and FOO_FILE to be set, as it is unclear which one should be
+// used.
+//
+// Variables which are not referenced are left alone, so that variables which
+// merely end in _FILE, e.g. LOG_FILE or SSL_CERT_FILE, are not read as
+// secrets.
+func resolveFileVars(data map[string]string, referenced map[string]bool) error {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, fileKey := range keys {
+		key := strings.TrimSuffix(fileKey, fileSuffix)
+		if key == fileKey || key == "" || data[fileKey] == "" || !referenced[key] {
+			continue
+		}
+
+		if data[key] != "" {
+			return fmt.Errorf("both %s and %s are set", key, fileKey)
+		}
+
+		value, err := readSecret(data[fileKey])
+		if err != nil {
+			return fmt.Errorf("couldn't fill %s from %s: %v", key, fileKey, err)
+		}
+		data[key] = value
+	}
+
+	return nil
+}
+
+// referencedVars returns the names of the variables referenced by the
+// templates of t, either as fields of the data, e.g. {{ .FOO }}, or through
+// the env and index functions, e.g. {{ env "FOO" }}. Names may be included
+// which are not variables, e.g. fields referenced within range.
+func referencedVars(t *template.Template) map[string]bool {
+	vars := make(map[string]bool)
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil {
+			walkVars(tmpl.Tree.Root, vars)
+		}
+	}
+	return vars
+}
+
+// walkVars adds the names of the variables referenced under node to vars.
+func walkVars(node parse.Node, vars map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkVars(child, vars)
+		}
+	case *parse.ActionNode:
+		walkVars(n.Pipe, vars)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, vars)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, vars)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, vars)
+	case *parse.TemplateNode:
+		walkVars(n.Pipe, vars)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkVars(cmd, vars)
+		}
+	case *parse.CommandNode:
+		if name, ok := lookupName(n.Args); ok {
+			vars[name] = true
+		}
+		for _, arg := range n.Args {
+			walkVars(arg, vars)
+		}
+	case *parse.FieldNode:
+		vars[n.Ident[0]] = true
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			vars[n.Ident[1]] = true
+		}
+	case *parse.ChainNode:
+		walkVars(n.Node, vars)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, vars map[string]bool) {
+	walkVars(n.Pipe, vars)
+	walkVars(n.List, vars)
+	walkVars(n.ElseList, vars)
+}
+
+// lookupName returns the name of the variable looked up by a command with the
+// given arguments, if it is either env "FOO" or index . "FOO".
+func lookupName(args []parse.Node) (string, bool) {
+	if len(args) == 0 {
+		return "", false
+	}
+	ident, ok := args[0].(*parse.IdentifierNode)
+	if !ok {
+		return "", false
+	}
+
+	var name parse.Node
+	switch {
+	case ident.Ident == "env" && len(args) == 2:
+		name = args[1]
+	case ident.Ident == "index" && len(args) == 3:
+		if _, ok := args[1].(*parse.DotNode); !ok {
+			return "", false
+		}
+		name = args[2]
+	default:
+		return "", false
+	}
+
+	s, ok := name.(*parse.StringNode)
+	if !ok {
+		return "", false
+	}
+	return s.Text, true
+}
+
+// readSecret returns the content of the file at path, without trailing
+// newlines.
+func readSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func envToMap() map[string]string {
+	envMap := make(map[string]string)
+
+	for _, v := range os.Environ() {
+		split_v := strings.SplitN(v, "=", 2)
+		envMap[split_v[0]] = split_v[1]
+	}
+
+	return envMap
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadData(t *testing.T) {
+	tests := []struct {
+		name     string
+		envFiles []string
+		env      map[string]string
+		// referenced are the variables referenced by the templates.
+		referenced []string
+		expected   map[string]string
+		// expectedErr is a substring of the expected error.
+		expectedErr string
+	}{{
+		name:       "env files, later ones take precedence",
+		envFiles:   []string{"testdata/secrets/base.env", "testdata/secrets/override.env"},
+		referenced: []string{"OFGA_TOKEN"},
+		expected: map[string]string{
+			"LOG_LEVEL":       "debug",
+			"OFGA_API_HOST":   "openfga",
+			"OFGA_TOKEN_FILE": "testdata/secrets/ofga-token",
+			"OFGA_TOKEN":      "secret-token",
+		},
+	}, {
+		name:       "process environment takes precedence over env files",
+		envFiles:   []string{"testdata/secrets/base.env"},
+		env:        map[string]string{"LOG_LEVEL": "warn"},
+		referenced: []string{"OFGA_TOKEN"},
+		expected: map[string]string{
+			"LOG_LEVEL":  "warn",
+			"OFGA_TOKEN": "secret-token",
+		},
+	}, {
+		name:       "variable filled from file",
+		env:        map[string]string{"DB_PASSWORD_FILE": "testdata/secrets/db-password"},
+		referenced: []string{"DB_PASSWORD"},
+		expected: map[string]string{
+			"DB_PASSWORD": "hunter2",
+		},
+	}, {
+		name:       "empty file variable is ignored",
+		env:        map[string]string{"DB_PASSWORD_FILE": ""},
+		referenced: []string{"DB_PASSWORD"},
+		expected: map[string]string{
+			"DB_PASSWORD": "",
+		},
+	}, {
+		name: "both variable and file variable set",
+		env: map[string]string{
+			"DB_PASSWORD":      "hunter3",
+			"DB_PASSWORD_FILE": "testdata/secrets/db-password",
+		},
+		referenced:  []string{"DB_PASSWORD"},
+		expectedErr: "both DB_PASSWORD and DB_PASSWORD_FILE are set",
+	}, {
+		name:        "missing file",
+		env:         map[string]string{"DB_PASSWORD_FILE": "testdata/secrets/missing"},
+		referenced:  []string{"DB_PASSWORD"},
+		expectedErr: "couldn't fill DB_PASSWORD from DB_PASSWORD_FILE",
+	}, {
+		name: "unreferenced file variables are left alone",
+		env: map[string]string{
+			"DB_PASSWORD_FILE": "testdata/secrets/missing",
+			"DB_PASSWORD":      "hunter3",
+		},
+		expected: map[string]string{
+			"DB_PASSWORD":      "hunter3",
+			"DB_PASSWORD_FILE": "testdata/secrets/missing",
+		},
+	}, {
+		name:        "missing env file",
+		envFiles:    []string{"testdata/secrets/missing.env"},
+		expectedErr: "couldn't read env file",
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unsetenv(t, "LOG_LEVEL", "OFGA_API_HOST", "OFGA_TOKEN", "OFGA_TOKEN_FILE", "DB_PASSWORD", "DB_PASSWORD_FILE")
+			for k, v := range test.env {
+				t.Setenv(k, v)
+			}
+
+			data, err := loadData(test.envFiles)
+			if err == nil {
+				referenced := make(map[string]bool)
+				for _, k := range test.referenced {
+					referenced[k] = true
+				}
+				err = resolveFileVars(data, referenced)
+			}
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for k, v := range test.expected {
+				if data[k] != v {
+					t.Errorf("expected %s=%q, got %q", k, v, data[k])
+				}
+			}
+		})
+	}
+}
+
+func TestReferencedVars(t *testing.T) {
+	text := `{{ .A }}{{ env "B" }}{{ index . "C" }}{{ if .D }}{{ range .E | split "," }}{{ $.F }}{{ end }}{{ end }}` +
+		`{{ define "x" }}{{ .G.H }}{{ end }}{{ with env "I" | default .J }}{{ end }}`
+	tmpl, err := newTemplate("test", nil, false).Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := referencedVars(tmpl)
+	for _, name := range []string{"A", "B", "C", "D", "E", "F", "G", "I", "J"} {
+		if !vars[name] {
+			t.Errorf("expected %s to be referenced", name)
+		}
+	}
+	if vars["H"] {
+		t.Errorf("unexpected reference to H")
+	}
+}
+
+func TestReadEnvFileInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invalid.env")
+	if err := os.WriteFile(path, []byte("# comment\n\nLOG_LEVEL\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := readEnvFile(path)
+	if err == nil || !strings.Contains(err.Error(), path+":3: expected KEY=VALUE") {
+		t.Fatalf("expected parse error, got %v", err)
+	}
+}
+
+func TestRenderSecrets(t *testing.T) {
+	unsetenv(t, "LOG_LEVEL", "OFGA_API_HOST", "OFGA_TOKEN", "OFGA_TOKEN_FILE")
+
+	data, err := loadData([]string{"testdata/secrets/base.env"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input, err := os.ReadFile(filepath.Join("testdata", "secrets.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := render(&buf, "secrets", string(input), data, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "secrets.golden")
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(expected) {
+		t.Errorf("output does not match %s:\n%s", golden, buf.String())
+	}
+}
+
+// unsetenv unsets the given variables for the duration of the test, so that
+// the test environment does not affect the result. Unlike variables set to an
+// empty string, unset variables do not override those from env files.
+func unsetenv(t *testing.T, keys ...string) {
+	for _, k := range keys {
+		// Setenv restores the original value at the end of the test.
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+}
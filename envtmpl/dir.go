@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const templateExt = ".tmpl"
+
+// dirTemplate is a template file found in the source directory.
+type dirTemplate struct {
+	// path is the path of the file relative to the source directory.
+	path string
+	mode fs.FileMode
+}
+
+// isPartial reports whether the template only holds definitions to be
+// included by other templates, in which case it is not rendered on its own.
+func (t dirTemplate) isPartial() bool {
+	return strings.HasPrefix(filepath.Base(t.path), "_")
+}
+
+// output returns the path of the rendered file relative to the destination
+// directory.
+func (t dirTemplate) output() string {
+	return strings.TrimSuffix(t.path, templateExt)
+}
+
+// isConfig reports whether the rendered file is validated with -validate. Only
+// the YAML files at the top of the destination directory are, as nested ones
+// such as dynamicconfig/*.yaml follow other schemas.
+func (t dirTemplate) isConfig() bool {
+	out := t.output()
+	return filepath.Dir(out) == "." && filepath.Ext(out) == ".yaml"
+}
+
+// renderDir renders every *.tmpl file under src into dst, keeping their
+// relative paths and file modes and stripping the .tmpl extension.
+//
+// All the templates are parsed into a single set, so templates defined with
+// {{ define "x" }} in one file can be included with {{ template "x" . }} in any
+// other. Files whose name starts with "_" are only parsed for their
+// definitions and are not rendered.
+//
+// Nothing is written unless every template renders successfully and, with
+// opts.validate, every configuration file is valid.
+func renderDir(src string, dst string, data map[string]string, opts options) error {
+	var templates []dirTemplate
+	set := newTemplate("", data, opts.strict)
+
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != templateExt {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("couldn't read input file: %v", err)
+		}
+
+		if _, err := set.New(rel).Parse(string(text)); err != nil {
+			return err
+		}
+
+		templates = append(templates, dirTemplate{path: rel, mode: info.Mode().Perm()})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := resolveFileVars(data, referencedVars(set)); err != nil {
+		return err
+	}
+
+	rendered := make(map[string][]byte)
+	for _, t := range templates {
+		if t.isPartial() {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if err := set.ExecuteTemplate(&buf, t.path, data); err != nil {
+			return fmt.Errorf("couldn't render template: %v", err)
+		}
+		rendered[t.path] = buf.Bytes()
+	}
+
+	if opts.validate != nil {
+		configs := make(map[string][]byte)
+		for _, t := range templates {
+			if !t.isPartial() && t.isConfig() {
+				configs[filepath.Join(dst, t.output())] = rendered[t.path]
+			}
+		}
+
+		if err := validateOutput(opts.validate, configs, opts.warnings); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range templates {
+		if t.isPartial() {
+			continue
+		}
+
+		out := filepath.Join(dst, t.output())
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return fmt.Errorf("couldn't create output directory: %v", err)
+		}
+
+		if err := os.WriteFile(out, rendered[t.path], t.mode); err != nil {
+			return fmt.Errorf("couldn't write output file: %v", err)
+		}
+
+		// WriteFile does not change the mode of existing files.
+		if err := os.Chmod(out, t.mode); err != nil {
+			return fmt.Errorf("couldn't set mode of output file: %v", err)
+		}
+	}
+
+	return nil
+}
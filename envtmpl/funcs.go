@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// funcMap returns the helper functions available in templates. Variables are
+// looked up in data.
+func funcMap(data map[string]string) template.FuncMap {
+	return template.FuncMap{
+		"env": func(name string) string {
+			return data[name]
+		},
+		"file":     file,
+		"default":  defaultValue,
+		"required": required,
+		"toYaml":   toYaml,
+		"split":    split,
+		"b64dec":   b64dec,
+		"atoi":     atoi,
+		"quote":    strconv.Quote,
+		"indent":   indent,
+		"nindent":  nindent,
+	}
+}
+
+// defaultValue returns value, or def if value is empty.
+//
+//	{{ env "LOG_LEVEL" | default "info" }}
+func defaultValue(def interface{}, value interface{}) interface{} {
+	if isEmpty(value) {
+		return def
+	}
+	return value
+}
+
+// required returns value, or fails rendering with the given message if value
+// is empty.
+//
+//	{{ required "DB_HOST must be set" .DB_HOST }}
+func required(msg string, value interface{}) (interface{}, error) {
+	if isEmpty(value) {
+		return nil, errors.New(msg)
+	}
+	return value, nil
+}
+
+// file returns the content of the file at path without trailing newlines, e.g.
+// a secret mounted into the container.
+//
+//	token: {{ file "/run/secrets/ofga-token" | quote }}
+func file(path string) (string, error) {
+	content, err := readSecret(path)
+	if err != nil {
+		return "", fmt.Errorf("file: %w", err)
+	}
+	return content, nil
+}
+
+// toYaml returns the YAML representation of value, without a trailing newline.
+//
+//	hosts: {{ .DB_HOSTS | split "," | toYaml }}
+func toYaml(value interface{}) (string, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// indent prefixes every line of s with the given number of spaces.
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// nindent is like indent, but also prepends a newline. It is convenient to
+// nest the output of toYaml.
+//
+//	namespaces: {{ .NAMESPACES | split "," | toYaml | nindent 2 }}
+func nindent(spaces int, s string) string {
+	return "\n" + indent(spaces, s)
+}
+
+// split splits s around each instance of sep. Unlike strings.Split, an empty s
+// yields an empty list.
+//
+//	{{ range .NAMESPACES | split "," }}...{{ end }}
+func split(sep string, s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, sep)
+}
+
+// b64dec decodes a standard base64 encoded string.
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(data), nil
+}
+
+// atoi converts s to an integer.
+func atoi(s string) (int, error) {
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("atoi: %w", err)
+	}
+	return i, nil
+}
+
+// isEmpty reports whether value is nil or the zero value of its type, or an
+// empty slice or map.
+func isEmpty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
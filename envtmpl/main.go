@@ -1,43 +1,143 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/template"
 )
 
 func main() {
-	if len(os.Args) != 3 {
+	strict := flag.Bool("strict", false, "fail when the template references a variable that is not set")
+	var envFiles stringsFlag
+	flag.Var(&envFiles, "env-file", "read variables from the given KEY=VALUE file. may be repeated, the process environment takes precedence")
+	validate := flag.String("validate", "", "validate the rendered output against the given schema before writing it. one of: "+strings.Join(validatorNames(), ", "))
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: envtmpl [flags] <input> <output>\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       envtmpl [flags] <input-dir> <output-dir>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 2 {
 		fmt.Println("ERROR: incorrect number of arguments provided.")
 		os.Exit(1)
 	}
 
-	tmpl, err := os.ReadFile(os.Args[1])
+	validateFunc, err := getValidator(*validate)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := options{
+		strict:   *strict,
+		validate: validateFunc,
+		warnings: os.Stdout,
+	}
+
+	data, err := loadData(envFiles)
+	if err != nil {
+		fmt.Printf("ERROR: %v\n", err)
+		os.Exit(1)
+	}
+
+	info, err := os.Stat(flag.Arg(0))
 	if err != nil {
-		fmt.Printf("ERROR: couldn't read input file: %v\n", err)
+		fmt.Printf("ERROR: couldn't read input: %v\n", err)
 		os.Exit(1)
 	}
 
-	envMap := envToMap()
-	t := template.Must(template.New("tmpl").Parse(string(tmpl)))
+	if info.IsDir() {
+		err = renderDir(flag.Arg(0), flag.Arg(1), data, opts)
+	} else {
+		err = renderFile(flag.Arg(0), flag.Arg(1), data, opts)
+	}
 
-	output, err := os.Create(os.Args[2])
 	if err != nil {
-		fmt.Printf("ERROR: couldn't create output file: %v\n", err)
+		fmt.Printf("ERROR: %v\n", err)
 		os.Exit(1)
 	}
+}
 
-	t.Execute(output, envMap)
+// options control how templates are rendered.
+type options struct {
+	// strict makes referencing a missing key an error.
+	strict bool
+	// validate, if set, checks the rendered configuration files before they
+	// are written.
+	validate validateFunc
+	// warnings, if set, receives the unknown keys found by validate, which
+	// are ignored by the server and don't fail rendering.
+	warnings io.Writer
 }
 
-func envToMap() map[string]string {
-	envMap := make(map[string]string)
+// stringsFlag is a flag.Value which can be set multiple times.
+type stringsFlag []string
+
+// String implements flag.Value.
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set implements flag.Value.
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// newTemplate returns an empty template set with the helper functions, which
+// look variables up in data. In strict mode, referencing a missing key is an
+// error instead of rendering "<no value>".
+func newTemplate(name string, data map[string]string, strict bool) *template.Template {
+	t := template.New(name).Funcs(funcMap(data))
+	if strict {
+		t = t.Option("missingkey=error")
+	}
+	return t
+}
+
+// render executes the template text with the given data, writing the result to
+// w.
+func render(w io.Writer, name string, text string, data map[string]string, strict bool) error {
+	t, err := newTemplate(name, data, strict).Parse(text)
+	if err != nil {
+		return err
+	}
+
+	if err := resolveFileVars(data, referencedVars(t)); err != nil {
+		return err
+	}
+
+	return t.Execute(w, data)
+}
+
+// renderFile renders the template file at src into the file at dst.
+func renderFile(src string, dst string, data map[string]string, opts options) error {
+	tmpl, err := os.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("couldn't read input file: %v", err)
+	}
+
+	// Render into memory first, so that no partial output is left behind on
+	// errors.
+	var buf bytes.Buffer
+	if err := render(&buf, src, string(tmpl), data, opts.strict); err != nil {
+		return fmt.Errorf("couldn't render template: %v", err)
+	}
+
+	if opts.validate != nil {
+		if err := validateOutput(opts.validate, map[string][]byte{dst: buf.Bytes()}, opts.warnings); err != nil {
+			return err
+		}
+	}
 
-	for _, v := range os.Environ() {
-		split_v := strings.SplitN(v, "=", 2)
-		envMap[split_v[0]] = split_v[1]
+	if err := os.WriteFile(dst, buf.Bytes(), 0666); err != nil {
+		return fmt.Errorf("couldn't write output file: %v", err)
 	}
 
-	return envMap
+	return nil
 }
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+var testEnv = map[string]string{
+	"AUTH_ENABLED":           "true",
+	"ADMIN_GROUPS":           "admins",
+	"OPEN_ACCESS_NAMESPACES": "workshop,sandbox",
+	"OFGA_API_HOST":          "openfga",
+	"OFGA_API_PORT":          " 8080",
+	"OFGA_TOKEN_B64":         "c2VjcmV0",
+	"NUM_HISTORY_SHARDS":     "",
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		strict bool
+		// expectedErr is a substring of the expected error. If empty, the
+		// output is compared against testdata/<name>.golden.
+		expectedErr string
+	}{
+		{name: "config", strict: true},
+		{name: "missing", strict: false},
+		{name: "missing", strict: true, expectedErr: `map has no entry for key "AUTH_ENABLD"`},
+		{name: "required", strict: true, expectedErr: "OFGA_TOKEN must be set"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", test.name+".tmpl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			err = render(&buf, test.name, string(input), testEnv, test.strict)
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			golden := filepath.Join("testdata", test.name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != string(expected) {
+				t.Errorf("output does not match %s:\n%s", golden, buf.String())
+			}
+		})
+	}
+}
+
+func TestRenderDir(t *testing.T) {
+	dst := t.TempDir()
+	if err := renderDir(filepath.Join("testdata", "dir"), dst, testEnv, options{strict: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	golden := filepath.Join("testdata", "dir.golden")
+	if *update {
+		if err := os.RemoveAll(golden); err != nil {
+			t.Fatal(err)
+		}
+		if err := copyDir(dst, golden); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := readDir(t, golden)
+	actual := readDir(t, dst)
+	if len(actual) != len(expected) {
+		t.Fatalf("expected files %v, got %v", keys(expected), keys(actual))
+	}
+
+	for path, want := range expected {
+		got, ok := actual[path]
+		if !ok {
+			t.Errorf("missing output file %s", path)
+			continue
+		}
+		if got.content != want.content {
+			t.Errorf("output %s does not match golden file:\n%s", path, got.content)
+		}
+		if got.mode != want.mode {
+			t.Errorf("output %s has mode %v, expected %v", path, got.mode, want.mode)
+		}
+	}
+}
+
+func TestRenderDirError(t *testing.T) {
+	dst := t.TempDir()
+	env := map[string]string{"AUTH_ENABLED": "true"}
+
+	err := renderDir(filepath.Join("testdata", "dir"), dst, env, options{strict: true})
+	if err == nil || !strings.Contains(err.Error(), `map has no entry for key "OFGA_API_HOST"`) {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no output on error, got %d entries", len(entries))
+	}
+}
+
+func TestRenderFileValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		env  map[string]string
+		// expectedErrs are substrings of the expected error. If empty, the
+		// output must be written.
+		expectedErrs []string
+		// expectedWarnings are substrings of the expected warnings.
+		expectedWarnings []string
+	}{{
+		name: "valid",
+		src:  filepath.Join("..", "temporal-server", "config", "development.yaml"),
+	}, {
+		name: "invalid",
+		src:  filepath.Join("testdata", "invalid.tmpl"),
+		env: map[string]string{
+			"LOG_LEVEL":          "info",
+			"AUTH_ENABLED":       "maybe",
+			"OFGA_API_HOST":      "openfga",
+			"OFGA_API_PORT":      "8080",
+			"NUM_HISTORY_SHARDS": "four",
+		},
+		expectedErrs: []string{
+			"line 5: cannot unmarshal !!str `maybe` into bool",
+			"line 11: cannot unmarshal !!str `four` into int32",
+		},
+		expectedWarnings: []string{
+			"line 12: field defaultStor not found in type config.Persistence, it is ignored by the server",
+		},
+	}}
+
+	validate, err := getValidator("temporal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "docker.yaml")
+
+			var warnings bytes.Buffer
+			err := renderFile(test.src, dst, test.env, options{strict: true, validate: validate, warnings: &warnings})
+			_, statErr := os.Stat(dst)
+			for _, expected := range test.expectedWarnings {
+				if !strings.Contains(warnings.String(), "WARNING: "+dst+": "+expected) {
+					t.Errorf("expected warning containing %q, got %q", expected, warnings.String())
+				}
+			}
+			if len(test.expectedErrs) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if statErr != nil {
+					t.Errorf("expected output to be written: %v", statErr)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			for _, expected := range test.expectedErrs {
+				if !strings.Contains(err.Error(), dst+": "+expected) {
+					t.Errorf("expected error containing %q, got %v", expected, err)
+				}
+			}
+			if strings.Contains(err.Error(), "not found in type") {
+				t.Errorf("expected unknown keys not to be errors, got %v", err)
+			}
+			if !os.IsNotExist(statErr) {
+				t.Errorf("expected no output on validation error, got %v", statErr)
+			}
+		})
+	}
+}
+
+func TestRenderFileValidateUnknownKeys(t *testing.T) {
+	config, err := os.ReadFile(filepath.Join("..", "temporal-server", "config", "development.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys left over from older versions of the config don't fail rendering.
+	src := filepath.Join(t.TempDir(), "docker.yaml.tmpl")
+	if err := os.WriteFile(src, append(config, "\nkafka:\n  tls:\n    enabled: false\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	validate, err := getValidator("temporal")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := filepath.Join(t.TempDir(), "docker.yaml")
+	var warnings bytes.Buffer
+	if err := renderFile(src, dst, nil, options{validate: validate, warnings: &warnings}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dst); err != nil {
+		t.Errorf("expected output to be written: %v", err)
+	}
+	if !strings.Contains(warnings.String(), "field kafka not found in type") {
+		t.Errorf("expected warning about kafka, got %q", warnings.String())
+	}
+}
+
+func TestGetValidatorUnknown(t *testing.T) {
+	_, err := getValidator("kubernetes")
+	if err == nil || !strings.Contains(err.Error(), `unknown schema "kubernetes", must be one of: temporal`) {
+		t.Fatalf("expected unknown schema error, got %v", err)
+	}
+}
+
+type testFile struct {
+	content string
+	mode    os.FileMode
+}
+
+// readDir returns the content and mode of every file under dir, keyed by their
+// relative path.
+func readDir(t *testing.T, dir string) map[string]testFile {
+	files := make(map[string]testFile)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[rel] = testFile{content: string(content), mode: info.Mode().Perm()}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// copyDir copies the files under src to dst, keeping their modes.
+func copyDir(src string, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(src, path)
+		out := filepath.Join(dst, rel)
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(out, content, info.Mode().Perm())
+	})
+}
+
+func keys(files map[string]testFile) []string {
+	var k []string
+	for path := range files {
+		k = append(k, path)
+	}
+	return k
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+)
+
+// validateFunc checks the content of a rendered configuration file.
+type validateFunc func(data []byte) []authorizer.ValidationError
+
+// validators are the schemas that rendered files can be validated against with
+// -validate.
+var validators = map[string]validateFunc{
+	// temporal parses the file the same way as the Temporal Server image,
+	// also reporting unknown keys.
+	"temporal": authorizer.ValidateConfigWithAuth,
+}
+
+// validatorNames returns the sorted names of the available validators.
+func validatorNames() []string {
+	names := make([]string, 0, len(validators))
+	for name := range validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getValidator returns the validator with the given name, or nil if name is
+// empty.
+func getValidator(name string) (validateFunc, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	validate, ok := validators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q, must be one of: %s", name, strings.Join(validatorNames(), ", "))
+	}
+
+	return validate, nil
+}
+
+// validateOutput validates the rendered files, keyed by their output path, and
+// returns an error listing every invalid value found. Unknown keys are only
+// written to warnings, if it is not nil, as the server ignores them: failing
+// on them would stop existing deployments with leftover keys from starting.
+func validateOutput(validate validateFunc, files map[string][]byte, warnings io.Writer) error {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var problems []string
+	for _, path := range paths {
+		for _, err := range validate(files[path]) {
+			if err.UnknownKey {
+				if warnings != nil {
+					fmt.Fprintf(warnings, "WARNING: %s: %v, it is ignored by the server\n", path, err)
+				}
+				continue
+			}
+			problems = append(problems, fmt.Sprintf("  %s: %v", path, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n%s", strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
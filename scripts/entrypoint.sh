@@ -13,14 +13,21 @@ else
     export TEMPORAL_CLI_ADDRESS="${BIND_ON_IP}:7233"
 fi
 
-# Generate the correct config file.
-envtmpl /etc/temporal/config/development.yaml /etc/temporal/config/docker.yaml
-
-# Automatically setup Temporal Server (databases, Elasticsearch, default namespace) if "autosetup" is passed as an argument.
-for arg; do [[ ${arg} == autosetup ]] && /etc/temporal/auto-setup.sh && break ; done
-
-# Setup Temporal Server in development mode if "develop" is passed as an argument.
-for arg; do [[ ${arg} == develop ]] && /etc/temporal/setup-develop.sh && break ; done
+# Generate the correct config file, failing early if it holds invalid values for
+# the Temporal Server. Unknown keys, which the server ignores, are only warned
+# about.
+envtmpl --validate temporal /etc/temporal/config/development.yaml /etc/temporal/config/docker.yaml
+
+# Automatically set up Temporal Server (databases, schema, default namespace) if "autosetup" or "develop" is passed as
+# an argument.
+: "${DEFAULT_NAMESPACE:=default}"
+: "${DEFAULT_NAMESPACE_RETENTION:=24h}"
+for arg; do
+    if [[ ${arg} == autosetup || ${arg} == develop ]]; then
+        temporal-server --env docker setup --namespace "${DEFAULT_NAMESPACE}" --namespace-retention "${DEFAULT_NAMESPACE_RETENTION}"
+        break
+    fi
+done
 
 # Run bash instead of Temporal Server if "bash" is passed as an argument (convenient to debug docker image).
 for arg; do [[ ${arg} == bash ]] && bash && exit 0 ; done
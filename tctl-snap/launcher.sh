@@ -6,4 +6,7 @@ then
   echo $'active: local\naliases: {}\nversion: 2\n' > $SNAP_USER_DATA/.config/temporalio/tctl.yaml
 fi
 
+# Point tctl at the selected environment, unless overridden by the user.
+eval "$(tctl-env export)"
+
 exec "$@"
@@ -27,42 +27,13 @@ type tokenResponse struct {
 var ErrNoEmailScope = errgo.New("token scope must include email")
 var ErrEmailNotVerified = errgo.New("token email not verified")
 
-// ClientID returns the '<env>-google-client-id' snapctl configuration.
-func ClientID() (string, error) {
-	env := os.Getenv("TCTL_ENVIRONMENT")
-	clientID, err := GetSnapctlArg("google-client-id")
-	if err != nil {
-		return "", err
-	}
-
-	if clientID == "" {
-		return "", fmt.Errorf("no google-client-id found for %v environment. use 'sudo snap set tctl %v-google-client-id=\"<client_id>\"'", env, env)
-	}
-
-	return clientID, nil
-}
-
-// ClientSecret returns the '<env>-google-client-secret' snapctl configuration.
-func ClientSecret() (string, error) {
-	env := os.Getenv("TCTL_ENVIRONMENT")
-	clientSecret, err := GetSnapctlArg("google-client-secret")
-	if err != nil {
-		return "", err
-	}
-
-	if clientSecret == "" {
-		return "", fmt.Errorf("no google-client-secret found for %v environment. use 'sudo snap set tctl %v-google-client-secret=\"<client_secret>\"'", env, env)
-	}
-
-	return clientSecret, nil
-}
-
-// FetchValidToken checks for the existence of a valid Google OAuth access token.
+// FetchValidToken checks for the existence of a valid OAuth access token for
+// the given environment.
 // If found, it returns the token if it is not expired.
 // If expired, it refreshes the access token and returns it.
-func FetchValidToken(clientID string, clientSecret string) (string, error) {
-	path := os.Getenv("SNAP_USER_DATA")
-	accessToken, err := readTokenFromFile(path, "access")
+func FetchValidToken(env *Environment) (string, error) {
+	path := DataDir()
+	accessToken, err := readTokenFromFile(path, env.Name, "access")
 	if err != nil {
 		return "", err
 	}
@@ -75,7 +46,7 @@ func FetchValidToken(clientID string, clientSecret string) (string, error) {
 		}
 
 		// Refresh access token is a refresh token is available
-		refreshToken, err := readTokenFromFile(path, "refresh")
+		refreshToken, err := readTokenFromFile(path, env.Name, "refresh")
 		if err != nil {
 			if !errors.Is(err, os.ErrNotExist) {
 				fmt.Fprintf(os.Stderr, "error reading refresh token from file at path: %v, %v", path, err)
@@ -84,7 +55,7 @@ func FetchValidToken(clientID string, clientSecret string) (string, error) {
 			return "", err
 		}
 
-		respToken, err := refreshAccessToken(clientID, clientSecret, refreshToken)
+		respToken, err := refreshAccessToken(env, refreshToken)
 		if err != nil {
 			return "", err
 		}
@@ -100,7 +71,7 @@ func FetchValidToken(clientID string, clientSecret string) (string, error) {
 // checking that the required scope, email and expiry time
 // restrictions exist.
 func verifyToken(accessToken string) error {
-	token, err := getTokenInfo(accessToken)
+	token, err := GetTokenInfo(accessToken)
 	if err != nil {
 		return fmt.Errorf("error fetching token info: %v. use tctl login to refresh token", err)
 	}
@@ -128,12 +99,15 @@ func verifyToken(accessToken string) error {
 	return nil
 }
 
-// WriteTokenToFile writes an access token to a file
+// WriteTokenToFile writes an access token for the given environment to a file
 // in the specified directory.
-func WriteTokenToFile(directory string, token string, token_type string) error {
-	env := os.Getenv("TCTL_ENVIRONMENT")
-	filePath := filepath.Join(directory, fmt.Sprintf("%v_%v_token.txt", env, token_type))
-	file, err := os.Create(filePath)
+func WriteTokenToFile(directory string, env string, token string, token_type string) error {
+	if err := ensureDir(directory); err != nil {
+		return err
+	}
+
+	filePath := tokenFilePath(directory, env, token_type)
+	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
@@ -148,21 +122,32 @@ func WriteTokenToFile(directory string, token string, token_type string) error {
 	return nil
 }
 
-// readTokenFromFile reads an access token from a file
+// ReadAccessToken returns the access token stored for the given environment,
+// without checking whether it is still valid.
+func ReadAccessToken(env *Environment) (string, error) {
+	return readTokenFromFile(DataDir(), env.Name, "access")
+}
+
+// readTokenFromFile reads an access token for the given environment from a file
 // in the specified directory.
-func readTokenFromFile(directory string, token_type string) (string, error) {
-	env := os.Getenv("TCTL_ENVIRONMENT")
-	filePath := filepath.Join(directory, fmt.Sprintf("%v_%v_token.txt", env, token_type))
+func readTokenFromFile(directory string, env string, token_type string) (string, error) {
+	filePath := tokenFilePath(directory, env, token_type)
 	data, err := ioutil.ReadFile(filePath)
 
 	if err != nil {
-		return "", fmt.Errorf("No valid token found. Please use tctl.%v login.", env)
+		return "", fmt.Errorf("No valid token found. Please use tctl login --env %v.", env)
 	}
 
 	token := string(data)
 	return token, nil
 }
 
+// tokenFilePath returns the path of the file holding the given type of token
+// for the given environment.
+func tokenFilePath(directory string, env string, token_type string) string {
+	return filepath.Join(directory, fmt.Sprintf("%v_%v_token.txt", env, token_type))
+}
+
 type TokenInfo struct {
 	Azp           string `json:"azp"`
 	Aud           string `json:"aud"`
@@ -175,8 +160,8 @@ type TokenInfo struct {
 	AccessType    string `json:"access_type"`
 }
 
-// getTokenInfo fetches a given access token's information.
-func getTokenInfo(accessToken string) (*TokenInfo, error) {
+// GetTokenInfo fetches a given access token's information.
+func GetTokenInfo(accessToken string) (*TokenInfo, error) {
 	url := "https://www.googleapis.com/oauth2/v3/tokeninfo"
 
 	req, err := http.NewRequest("GET", url, nil)
@@ -205,17 +190,20 @@ func getTokenInfo(accessToken string) (*TokenInfo, error) {
 }
 
 // refreshAccessToken refreshes an access token using a refresh token and
-// the Google OAuth2 token endpoint.
-func refreshAccessToken(clientID string, clientSecret string, refreshToken string) (*tokenResponse, error) {
-	tokenEndpoint := "https://oauth2.googleapis.com/token"
+// Google's token endpoint.
+func refreshAccessToken(env *Environment, refreshToken string) (*tokenResponse, error) {
+	provider, err := DiscoverProvider(GoogleIssuer)
+	if err != nil {
+		return nil, err
+	}
 
 	formData := url.Values{}
-	formData.Set("client_id", clientID)
-	formData.Set("client_secret", clientSecret)
+	formData.Set("client_id", env.ClientID)
+	formData.Set("client_secret", env.ClientSecret)
 	formData.Set("refresh_token", refreshToken)
 	formData.Set("grant_type", "refresh_token")
 
-	response, err := http.PostForm(tokenEndpoint, formData)
+	response, err := http.PostForm(provider.TokenEndpoint, formData)
 	if err != nil {
 		return nil, fmt.Errorf("request error: %s", err)
 	}
@@ -230,8 +218,8 @@ func refreshAccessToken(clientID string, clientSecret string, refreshToken strin
 		return nil, err
 	}
 
-	path := os.Getenv("SNAP_USER_DATA")
-	err = WriteTokenToFile(path, tokenResp.AccessToken, "access")
+	path := DataDir()
+	err = WriteTokenToFile(path, env.Name, tokenResp.AccessToken, "access")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error writing access token: %v\n", err)
 	}
@@ -242,10 +230,9 @@ func refreshAccessToken(clientID string, clientSecret string, refreshToken strin
 }
 
 // GetSnapctlArg retrieves a configuration value using the "snapctl get" command
-// with the specified argument and the current TCTL_ENVIRONMENT.
+// with the specified argument. Values of nested keys are returned as JSON.
 func GetSnapctlArg(arg string) (string, error) {
-	env := os.Getenv("TCTL_ENVIRONMENT")
-	execCmd := exec.Command("snapctl", "get", fmt.Sprintf("%v-%v", env, arg))
+	execCmd := exec.Command("snapctl", "get", arg)
 
 	output, err := execCmd.CombinedOutput()
 	if err != nil {
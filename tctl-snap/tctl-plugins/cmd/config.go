@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+const appName = "tctl"
+
+// defaultEnvironmentName is the name given to the environment built only from
+// flags and environment variables, when no environment is selected.
+const defaultEnvironmentName = "default"
+
+// InSnap reports whether the plugins are running inside the tctl snap.
+func InSnap() bool {
+	return os.Getenv("SNAP_NAME") != ""
+}
+
+// ConfigDir returns the directory holding the user's environments file. It is
+// SNAP_USER_DATA inside the snap and the XDG config directory otherwise.
+func ConfigDir() string {
+	if dir := os.Getenv("SNAP_USER_DATA"); dir != "" {
+		return dir
+	}
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns the directory where tokens are stored. It is SNAP_USER_DATA
+// inside the snap and the XDG data directory otherwise.
+func DataDir() string {
+	if dir := os.Getenv("SNAP_USER_DATA"); dir != "" {
+		return dir
+	}
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// xdgDir returns the application directory under the XDG base directory
+// defined by the given variable, falling back to the given path relative to
+// the user's home directory.
+func xdgDir(variable string, fallback string) string {
+	base := os.Getenv(variable)
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			home = "."
+		}
+		base = filepath.Join(home, fallback)
+	}
+	return filepath.Join(base, appName)
+}
+
+// ensureDir creates the given directory if it does not exist yet.
+func ensureDir(dir string) error {
+	return os.MkdirAll(dir, 0700)
+}
+
+// Flags holds the environment settings which can be overridden on the command
+// line.
+type Flags struct {
+	fs  *flag.FlagSet
+	env Environment
+}
+
+// RegisterFlags registers the flags which override the selected environment's
+// settings on the given flag set.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.env.Address, "address", "", "host:port of the Temporal frontend service")
+	fs.StringVar(&f.env.ClientID, "client-id", "", "OAuth client ID, leave empty if no login is required")
+	fs.StringVar(&f.env.ClientSecret, "client-secret", "", "OAuth client secret")
+	fs.StringVar(&f.env.TLSServerName, "tls-server-name", "", "override for the target server name")
+	fs.StringVar(&f.env.TLSCAPath, "tls-ca-path", "", "path to the server CA certificate")
+	fs.BoolVar(&f.env.TLSDisableHostVerification, "tls-disable-host-verification", false, "disable TLS host name verification")
+	return f
+}
+
+// Apply overrides the settings of env with the flags that were set.
+func (f *Flags) Apply(env *Environment) {
+	if f == nil {
+		return
+	}
+
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "address":
+			env.Address = f.env.Address
+		case "client-id":
+			env.ClientID = f.env.ClientID
+		case "client-secret":
+			env.ClientSecret = f.env.ClientSecret
+		case "tls-server-name":
+			env.TLSServerName = f.env.TLSServerName
+		case "tls-ca-path":
+			env.TLSCAPath = f.env.TLSCAPath
+		case "tls-disable-host-verification":
+			env.TLSDisableHostVerification = f.env.TLSDisableHostVerification
+		}
+	})
+}
+
+// isSet reports whether any of the flags was set.
+func (f *Flags) isSet() bool {
+	set := false
+	if f != nil {
+		f.fs.Visit(func(*flag.Flag) { set = true })
+	}
+	return set
+}
+
+// environmentVariables maps the variables which override the selected
+// environment's settings to the setting they override.
+var environmentVariables = map[string]func(env *Environment, value string){
+	"TCTL_ADDRESS":         func(env *Environment, value string) { env.Address = value },
+	"TCTL_CLIENT_ID":       func(env *Environment, value string) { env.ClientID = value },
+	"TCTL_CLIENT_SECRET":   func(env *Environment, value string) { env.ClientSecret = value },
+	"TCTL_TLS_SERVER_NAME": func(env *Environment, value string) { env.TLSServerName = value },
+	"TCTL_TLS_CA_PATH":     func(env *Environment, value string) { env.TLSCAPath = value },
+	"TCTL_TLS_DISABLE_HOST_VERIFICATION": func(env *Environment, value string) {
+		env.TLSDisableHostVerification, _ = strconv.ParseBool(value)
+	},
+}
+
+// applyEnvironmentVariables overrides the settings of env with the variables
+// that are set, and reports whether any of them was.
+func applyEnvironmentVariables(env *Environment) bool {
+	set := false
+	for variable, apply := range environmentVariables {
+		if value, ok := os.LookupEnv(variable); ok && value != "" {
+			apply(env, value)
+			set = true
+		}
+	}
+	return set
+}
+
+// ResolveEnvironment returns the selected environment with its settings
+// resolved in order of precedence from the given flags, the TCTL_* environment
+// variables, the user's environments file and, inside the snap, the snap
+// configuration.
+//
+// If no environment is selected, the settings are taken from the flags and
+// environment variables alone. The returned environment is nil if none of them
+// are set either.
+func ResolveEnvironment(flags *Flags) (*Environment, error) {
+	name, err := CurrentEnvironmentName()
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Environment{Name: defaultEnvironmentName}
+	if name != "" {
+		env, err = GetEnvironment(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	envSet := applyEnvironmentVariables(env)
+	flags.Apply(env)
+
+	if name == "" && !envSet && !flags.isSet() {
+		return nil, nil
+	}
+
+	return env, nil
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const environmentsFileName = "environments.json"
+
+// Environment describes a Temporal cluster that tctl can be pointed at, along
+// with the credentials needed to log in to it.
+type Environment struct {
+	// Name is the name used to select the environment, e.g. "prod".
+	Name string `json:"-"`
+	// Address is the host:port of the Temporal frontend service.
+	Address string `json:"address,omitempty"`
+	// ClientID is the OAuth client ID. If empty, the environment does not
+	// require login and requests are sent without an authorization header.
+	ClientID string `json:"client-id,omitempty"`
+	// ClientSecret is the OAuth client secret.
+	ClientSecret string `json:"client-secret,omitempty"`
+	// TLSServerName overrides the server name used to verify the frontend's
+	// certificate.
+	TLSServerName string `json:"tls-server-name,omitempty"`
+	// TLSCAPath is the path to the CA certificate of the frontend service.
+	TLSCAPath string `json:"tls-ca-path,omitempty"`
+	// TLSDisableHostVerification disables TLS host name verification.
+	TLSDisableHostVerification bool `json:"tls-disable-host-verification,omitempty"`
+}
+
+// RequiresLogin reports whether requests to the environment must carry an
+// access token.
+func (e Environment) RequiresLogin() bool {
+	return e.ClientID != ""
+}
+
+// environmentsFile is the on-disk representation of the user's environments.
+type environmentsFile struct {
+	Current      string                 `json:"current,omitempty"`
+	Environments map[string]Environment `json:"environments"`
+}
+
+// LoadEnvironments returns all the known environments, keyed by name.
+//
+// Environments are read from the user's environments file, which is managed
+// through 'tctl env', and, inside the snap, from the 'environments' snap
+// configuration, which is managed by the system administrator. Entries in the
+// user's file take precedence over the snap configuration.
+func LoadEnvironments() (map[string]Environment, error) {
+	envs, err := snapEnvironments()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := readEnvironmentsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	for name, env := range f.Environments {
+		envs[name] = env
+	}
+
+	for name, env := range envs {
+		env.Name = name
+		envs[name] = env
+	}
+
+	return envs, nil
+}
+
+// EnvironmentNames returns the sorted names of the given environments.
+func EnvironmentNames(envs map[string]Environment) []string {
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentEnvironmentName returns the name of the selected environment. The
+// TCTL_ENVIRONMENT variable takes precedence over the environment selected
+// through 'tctl login --env'.
+func CurrentEnvironmentName() (string, error) {
+	if env := os.Getenv("TCTL_ENVIRONMENT"); env != "" {
+		return env, nil
+	}
+
+	f, err := readEnvironmentsFile()
+	if err != nil {
+		return "", err
+	}
+
+	return f.Current, nil
+}
+
+// GetEnvironment returns the environment with the given name.
+func GetEnvironment(name string) (*Environment, error) {
+	envs, err := LoadEnvironments()
+	if err != nil {
+		return nil, err
+	}
+
+	env, ok := envs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown environment %q. use 'tctl env list' to see the available environments", name)
+	}
+
+	return &env, nil
+}
+
+// SetCurrentEnvironment selects the environment used by subsequent commands.
+func SetCurrentEnvironment(name string) error {
+	if _, err := GetEnvironment(name); err != nil {
+		return err
+	}
+
+	f, err := readEnvironmentsFile()
+	if err != nil {
+		return err
+	}
+
+	f.Current = name
+	return writeEnvironmentsFile(f)
+}
+
+// AddEnvironment adds the environment to the user's environments file,
+// replacing any existing environment with the same name.
+func AddEnvironment(env Environment) error {
+	if env.Name == "" {
+		return errors.New("environment name must not be empty")
+	}
+
+	f, err := readEnvironmentsFile()
+	if err != nil {
+		return err
+	}
+
+	f.Environments[env.Name] = env
+	return writeEnvironmentsFile(f)
+}
+
+// RemoveEnvironment removes the environment from the user's environments file.
+// Environments defined in the snap configuration can only be removed by the
+// system administrator.
+func RemoveEnvironment(name string) error {
+	f, err := readEnvironmentsFile()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := f.Environments[name]; !ok {
+		return fmt.Errorf("environment %q is not defined in %v", name, environmentsFilePath())
+	}
+
+	delete(f.Environments, name)
+	if f.Current == name {
+		f.Current = ""
+	}
+
+	return writeEnvironmentsFile(f)
+}
+
+// snapEnvironments reads the environments defined in the snap configuration,
+// e.g. through 'sudo snap set tctl environments.prod.address=<address>'. It
+// returns no environments when not running inside the snap.
+func snapEnvironments() (map[string]Environment, error) {
+	envs := make(map[string]Environment)
+	if !InSnap() {
+		return envs, nil
+	}
+
+	output, err := GetSnapctlArg("environments")
+	if err != nil {
+		return nil, fmt.Errorf("error reading environments from snap configuration: %v", err)
+	}
+
+	if output == "" {
+		return envs, nil
+	}
+
+	if err := json.Unmarshal([]byte(output), &envs); err != nil {
+		return nil, fmt.Errorf("error parsing environments from snap configuration: %v", err)
+	}
+
+	return envs, nil
+}
+
+func environmentsFilePath() string {
+	return filepath.Join(ConfigDir(), environmentsFileName)
+}
+
+func readEnvironmentsFile() (*environmentsFile, error) {
+	f := &environmentsFile{}
+
+	data, err := ioutil.ReadFile(environmentsFilePath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, f); err != nil {
+			return nil, fmt.Errorf("error parsing %v: %v", environmentsFilePath(), err)
+		}
+	}
+
+	if f.Environments == nil {
+		f.Environments = make(map[string]Environment)
+	}
+
+	return f, nil
+}
+
+func writeEnvironmentsFile(f *environmentsFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ensureDir(ConfigDir()); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(environmentsFilePath(), data, 0600)
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tokenTypes are the types of tokens stored for each environment. The refresh
+// token comes first, as revoking it also invalidates the access tokens issued
+// from it.
+var tokenTypes = []string{"refresh", "access"}
+
+// LogoutResult holds the outcome of logging out of an environment.
+type LogoutResult struct {
+	// Revoked lists the types of tokens that were revoked at the provider.
+	Revoked []string
+	// Removed lists the token files that were deleted.
+	Removed []string
+	// Errors lists the non-fatal errors encountered while logging out.
+	Errors []error
+}
+
+// StoredTokenEnvironments returns the sorted names of the environments for
+// which tokens are stored.
+func StoredTokenEnvironments() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(DataDir(), "*_token.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var envs []string
+	for _, path := range paths {
+		for _, tokenType := range tokenTypes {
+			name := strings.TrimSuffix(filepath.Base(path), fmt.Sprintf("_%v_token.txt", tokenType))
+			if name != filepath.Base(path) && !seen[name] {
+				seen[name] = true
+				envs = append(envs, name)
+			}
+		}
+	}
+
+	sort.Strings(envs)
+	return envs, nil
+}
+
+// Logout revokes the tokens stored for the given environment at Google's
+// revocation endpoint and deletes them from the local filesystem. The files
+// are deleted even if the revocation fails. Google is only contacted if there
+// are tokens to revoke.
+func Logout(env *Environment) *LogoutResult {
+	result := &LogoutResult{}
+	directory := DataDir()
+
+	type storedToken struct {
+		tokenType string
+		path      string
+		data      []byte
+		err       error
+	}
+	var tokens []storedToken
+	revocable := false
+	for _, tokenType := range tokenTypes {
+		path := tokenFilePath(directory, env.Name, tokenType)
+		data, err := ioutil.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		tokens = append(tokens, storedToken{tokenType: tokenType, path: path, data: data, err: err})
+		revocable = revocable || (err == nil && len(data) > 0)
+	}
+
+	var provider *Provider
+	if revocable {
+		var err error
+		provider, err = DiscoverProvider(GoogleIssuer)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("tokens not revoked: %v", err))
+		} else if provider.RevocationEndpoint == "" {
+			result.Errors = append(result.Errors, fmt.Errorf("tokens not revoked: %v has no revocation endpoint", GoogleIssuer))
+			provider = nil
+		}
+	}
+
+	for _, token := range tokens {
+		if token.err == nil && provider != nil && len(token.data) > 0 {
+			if err := revokeToken(provider, string(token.data), token.tokenType); err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("error revoking %v token: %v", token.tokenType, err))
+			} else {
+				result.Revoked = append(result.Revoked, token.tokenType)
+			}
+		}
+
+		if err := os.Remove(token.path); err != nil {
+			result.Errors = append(result.Errors, fmt.Errorf("error removing %v: %v", token.path, err))
+			continue
+		}
+		result.Removed = append(result.Removed, token.path)
+	}
+
+	return result
+}
+
+// revokeToken revokes the given token at the provider's revocation endpoint as
+// described in RFC 7009.
+func revokeToken(provider *Provider, token string, tokenType string) error {
+	formData := url.Values{}
+	formData.Set("token", token)
+	formData.Set("token_type_hint", tokenType+"_token")
+
+	response, err := http.PostForm(provider.RevocationEndpoint, formData)
+	if err != nil {
+		return fmt.Errorf("request error: %s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	// Some providers, including Google, reject tokens that are already expired
+	// or revoked instead of ignoring them as RFC 7009 requires.
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if json.NewDecoder(response.Body).Decode(&errResp) == nil && errResp.Error == "invalid_token" {
+		return nil
+	}
+
+	return fmt.Errorf("revocation failed with status code: %d", response.StatusCode)
+}
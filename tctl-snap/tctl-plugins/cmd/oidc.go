@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GoogleIssuer is the OAuth issuer used to log in. The Temporal server only
+// accepts Google access tokens, which are verified along with the ones stored
+// by the plugins through Google's tokeninfo endpoint, so the issuer is not
+// configurable.
+const GoogleIssuer = "https://accounts.google.com"
+
+// Provider holds the OAuth endpoints of an issuer.
+type Provider struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	RevocationEndpoint    string `json:"revocation_endpoint"`
+}
+
+// DiscoverProvider fetches the OpenID configuration of the given issuer.
+func DiscoverProvider(issuer string) (*Provider, error) {
+	url := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	response, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("request error: %s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching openid configuration from %v failed with status code: %d", url, response.StatusCode)
+	}
+
+	var provider Provider
+	if err := json.NewDecoder(response.Body).Decode(&provider); err != nil {
+		return nil, fmt.Errorf("error decoding openid configuration: %s", err)
+	}
+
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" {
+		return nil, fmt.Errorf("openid configuration of %v is missing the authorization or token endpoint", issuer)
+	}
+
+	return &provider, nil
+}
@@ -13,32 +13,26 @@ import (
 type provider struct{}
 
 func (p provider) GetHeaders(ctx context.Context) (map[string]string, error) {
-	env := os.Getenv("TCTL_ENVIRONMENT")
-	if env == "dev" {
-		return map[string]string{}, nil
-	}
-
-	clientID, err := cmd.ClientID()
+	env, err := cmd.ResolveEnvironment(nil)
 	if err != nil {
 		return map[string]string{}, err
 	}
 
-	if clientID == "" {
-		fmt.Fprintf(os.Stderr, "no google-client-id found for %v environment. use 'sudo snap set tctl %v-google-client-id=\"<client_id>\"'.\n", env, env)
+	// Without a selected environment, requests are sent unauthenticated.
+	if env == nil {
 		return map[string]string{}, nil
 	}
 
-	clientSecret, err := cmd.ClientSecret()
-	if err != nil {
-		return map[string]string{}, err
+	if !env.RequiresLogin() {
+		return map[string]string{}, nil
 	}
 
-	if clientSecret == "" {
-		fmt.Fprintf(os.Stderr, "no google-client-secret found for %v environment. use 'sudo snap set tctl %v-google-client-secret=\"<client_secret>\"'.\n", env, env)
+	if env.ClientSecret == "" {
+		fmt.Fprintf(os.Stderr, "no client-secret found for %v environment. use 'tctl env add %v --client-secret=\"<client_secret>\"'.\n", env.Name, env.Name)
 		return map[string]string{}, nil
 	}
 
-	token, err := cmd.FetchValidToken(clientID, clientSecret)
+	token, err := cmd.FetchValidToken(env)
 	if err != nil {
 		return map[string]string{}, err
 	}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/canonical/charmed-temporal-image/tctl-plugins/cmd"
+)
+
+const usage = `usage: tctl env <command> [arguments]
+
+commands:
+  list                   list the available environments
+  add <name> [flags]     add or update an environment
+  remove <name>          remove an environment
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "list":
+		err = list()
+	case "add":
+		err = add(os.Args[2:])
+	case "remove":
+		err = remove(os.Args[2:])
+	case "export":
+		err = export()
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+// list prints all the available environments, marking the selected one.
+func list() error {
+	envs, err := cmd.LoadEnvironments()
+	if err != nil {
+		return err
+	}
+
+	current, err := cmd.CurrentEnvironmentName()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range cmd.EnvironmentNames(envs) {
+		env := envs[name]
+		marker := " "
+		if name == current {
+			marker = "*"
+		}
+
+		login := "no login"
+		if env.RequiresLogin() {
+			login = "login via Google"
+		}
+
+		fmt.Fprintf(os.Stdout, "%v %-12v %-40v %v\n", marker, name, env.Address, login)
+	}
+
+	return nil
+}
+
+// add adds a new environment or updates the fields of an existing one which
+// are provided as flags.
+func add(args []string) error {
+	if len(args) < 1 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: tctl env add <name> [flags]")
+	}
+	name := args[0]
+
+	env := cmd.Environment{Name: name}
+	if existing, err := cmd.GetEnvironment(name); err == nil {
+		env = *existing
+	}
+
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	flags := cmd.RegisterFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	flags.Apply(&env)
+
+	if err := cmd.AddEnvironment(env); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "environment %v saved\n", name)
+	return nil
+}
+
+// remove removes an environment from the user's environments.
+func remove(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: tctl env remove <name>")
+	}
+
+	if err := cmd.RemoveEnvironment(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "environment %v removed\n", args[0])
+	return nil
+}
+
+// export prints the shell commands which point tctl at the selected
+// environment. It is used by the snap launcher.
+func export() error {
+	env, err := cmd.ResolveEnvironment(nil)
+	if err != nil || env == nil {
+		return err
+	}
+
+	disableHostVerification := ""
+	if env.TLSDisableHostVerification {
+		disableHostVerification = "true"
+	}
+
+	vars := [][2]string{
+		{"TEMPORAL_CLI_ADDRESS", env.Address},
+		{"TEMPORAL_CLI_TLS_SERVER_NAME", env.TLSServerName},
+		{"TEMPORAL_CLI_TLS_CA", env.TLSCAPath},
+		{"TEMPORAL_CLI_TLS_DISABLE_HOST_VERIFICATION", disableHostVerification},
+	}
+
+	// Variables which are already set by the user take precedence.
+	for _, v := range vars {
+		if v[1] == "" || os.Getenv(v[0]) != "" {
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "export %v='%v'\n", v[0], strings.ReplaceAll(v[1], "'", `'\''`))
+	}
+
+	return nil
+}
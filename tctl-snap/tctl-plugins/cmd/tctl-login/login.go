@@ -8,6 +8,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -20,49 +21,67 @@ import (
 	"github.com/skratchdot/open-golang/open"
 )
 
-const (
-	authEndpoint = "https://accounts.google.com/o/oauth2/auth"
-	scope        = "openid profile email"
-)
+const scope = "openid profile email"
 
 var (
 	server       *http.Server
 	shutdown     = make(chan struct{}) // Custom channel for graceful shutdown signal
 	state        = uuid.New().String()
-	clientID     string
-	clientSecret string
+	env          *cmd.Environment
+	provider     *cmd.Provider
 	codeVerifier string
 	redirectURI  string
 )
 
 func main() {
-	env := os.Getenv("TCTL_ENVIRONMENT")
-	if env == "dev" {
-		fmt.Fprintf(os.Stderr, "login command not defined for development environment.\n")
-		os.Exit(1)
-	}
+	envName := flag.String("env", "", "name of the environment to log in to and select for subsequent commands")
+	flags := cmd.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
 	var err error
-	clientID, err = cmd.ClientID()
+	if *envName != "" {
+		if err = cmd.SetCurrentEnvironment(*envName); err != nil {
+			fmt.Fprintf(os.Stderr, "error selecting environment: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stdout, "selected environment %v\n", *envName)
+	}
+
+	env, err = cmd.ResolveEnvironment(flags)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error reading snap argument '%v-google-client-id': %v", env, err)
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
 
-	clientSecret, err = cmd.ClientSecret()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error reading snap argument '%v-google-client-secret': %v", env, err)
+	if env == nil {
+		fmt.Fprintf(os.Stderr, "no environment selected. use 'tctl login --env <name>' to select one\n")
+		os.Exit(1)
+	}
+
+	if !env.RequiresLogin() {
+		fmt.Fprintf(os.Stdout, "environment %v does not require login\n", env.Name)
+		os.Exit(0)
+	}
+
+	if env.ClientSecret == "" {
+		fmt.Fprintf(os.Stderr, "no client-secret found for %v environment. use 'tctl env add %v --client-secret=\"<client_secret>\"'\n", env.Name, env.Name)
 		os.Exit(1)
 	}
 
 	// Error is ignored, as any failure to fetch a valid token will result in the initiation of the login flow
 	// to fetch a new token.
-	token, _ := cmd.FetchValidToken(clientID, clientSecret)
+	token, _ := cmd.FetchValidToken(env)
 	if token != "" {
 		fmt.Fprintf(os.Stdout, "valid access token fetched\n")
 		os.Exit(0)
 	}
 
+	provider, err = cmd.DiscoverProvider(cmd.GoogleIssuer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to login: %s\n", err)
+		os.Exit(1)
+	}
+
 	if err := getToken(); err != nil {
 		fmt.Fprintf(os.Stderr, "unable to login: %s\n", err)
 		os.Exit(1)
@@ -121,7 +140,7 @@ func generateAuthURL() string {
 	codeChallenge := generateCodeChallenge(codeVerifier)
 
 	queryParams := url.Values{}
-	queryParams.Add("client_id", clientID)
+	queryParams.Add("client_id", env.ClientID)
 	queryParams.Add("redirect_uri", redirectURI)
 	queryParams.Add("scope", scope)
 	queryParams.Add("response_type", "code")
@@ -129,7 +148,7 @@ func generateAuthURL() string {
 	queryParams.Add("code_challenge", codeChallenge)
 	queryParams.Add("code_challenge_method", "S256")
 
-	authURL := authEndpoint + "?" + queryParams.Encode()
+	authURL := provider.AuthorizationEndpoint + "?" + queryParams.Encode()
 	return authURL
 }
 
@@ -179,14 +198,14 @@ func handleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Store access token in snap application directory
-	path := os.Getenv("SNAP_USER_DATA")
-	err = cmd.WriteTokenToFile(path, tokenResp.AccessToken, "access")
+	// Store access token in the user's data directory
+	path := cmd.DataDir()
+	err = cmd.WriteTokenToFile(path, env.Name, tokenResp.AccessToken, "access")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing access token: %s\n", err)
 	}
 
-	err = cmd.WriteTokenToFile(path, tokenResp.RefreshToken, "refresh")
+	err = cmd.WriteTokenToFile(path, env.Name, tokenResp.RefreshToken, "refresh")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error writing refresh token: %s\n", err)
 	}
@@ -202,11 +221,9 @@ type tokenResponse struct {
 // exchangeCodeForToken exchanges an authorization code for an access token
 // using the OAuth 2.0 authorization code flow.
 func exchangeCodeForToken(authorizationCode string) (*tokenResponse, error) {
-	tokenEndpoint := "https://oauth2.googleapis.com/token"
-
 	formData := url.Values{}
-	formData.Set("client_id", clientID)
-	formData.Set("client_secret", clientSecret)
+	formData.Set("client_id", env.ClientID)
+	formData.Set("client_secret", env.ClientSecret)
 	formData.Set("code", authorizationCode)
 	formData.Set("redirect_uri", redirectURI)
 	formData.Set("grant_type", "authorization_code")
@@ -214,7 +231,7 @@ func exchangeCodeForToken(authorizationCode string) (*tokenResponse, error) {
 	// Include the code verifier in the token request
 	formData.Set("code_verifier", codeVerifier)
 
-	response, err := http.PostForm(tokenEndpoint, formData)
+	response, err := http.PostForm(provider.TokenEndpoint, formData)
 	if err != nil {
 		return nil, err
 	}
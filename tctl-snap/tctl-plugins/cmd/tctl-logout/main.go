@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/canonical/charmed-temporal-image/tctl-plugins/cmd"
+)
+
+func main() {
+	all := flag.Bool("all", false, "log out of all environments instead of only the selected one")
+	flags := cmd.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	envs, err := environmentsToLogout(*all, flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, env := range envs {
+		result := cmd.Logout(env)
+
+		if len(result.Removed) == 0 && len(result.Errors) == 0 {
+			fmt.Fprintf(os.Stdout, "%v: not logged in\n", env.Name)
+			continue
+		}
+
+		for _, tokenType := range result.Revoked {
+			fmt.Fprintf(os.Stdout, "%v: revoked %v token\n", env.Name, tokenType)
+		}
+		for _, path := range result.Removed {
+			fmt.Fprintf(os.Stdout, "%v: removed %v\n", env.Name, path)
+		}
+		for _, err := range result.Errors {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", env.Name, err)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// environmentsToLogout returns the selected environment, or every environment
+// with stored tokens if all is set.
+func environmentsToLogout(all bool, flags *cmd.Flags) ([]*cmd.Environment, error) {
+	if !all {
+		env, err := cmd.ResolveEnvironment(flags)
+		if err != nil {
+			return nil, err
+		}
+
+		if env == nil {
+			return nil, fmt.Errorf("no environment selected. use 'tctl logout --all' to log out of all environments")
+		}
+
+		return []*cmd.Environment{env}, nil
+	}
+
+	names, err := cmd.StoredTokenEnvironments()
+	if err != nil {
+		return nil, err
+	}
+
+	var envs []*cmd.Environment
+	for _, name := range names {
+		env, err := cmd.GetEnvironment(name)
+		if err != nil {
+			// Tokens of environments that were removed since logging in are
+			// still revoked.
+			env = &cmd.Environment{Name: name}
+		}
+		envs = append(envs, env)
+	}
+
+	return envs, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/canonical/charmed-temporal-image/tctl-plugins/cmd"
+)
+
+// claimsResponse is the response of the server's whoami endpoint.
+type claimsResponse struct {
+	System     string            `json:"system"`
+	Namespaces map[string]string `json:"namespaces"`
+}
+
+func main() {
+	serverURL := flag.String("server-url", "", "base URL of the Temporal server's introspection endpoint, e.g. https://<server_hostname>:7244. if set, the claims resolved by the server are shown too")
+	flags := cmd.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	env, err := cmd.ResolveEnvironment(flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	if env == nil || !env.RequiresLogin() {
+		fmt.Fprintf(os.Stdout, "no login required, requests are sent without an access token\n")
+		os.Exit(0)
+	}
+
+	// The stored token is inspected as is, without refreshing it, so that
+	// whoami shows the token tctl currently sends.
+	token, err := cmd.ReadAccessToken(env)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	tokenInfo, err := cmd.GetTokenInfo(token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error fetching token info, the access token may have expired: %v. use tctl login --env %v to refresh it\n", err, env.Name)
+		os.Exit(1)
+	}
+
+	if expired := printTokenInfo(env, tokenInfo); expired {
+		fmt.Fprintf(os.Stderr, "the access token has expired. use tctl login --env %v to refresh it\n", env.Name)
+		os.Exit(1)
+	}
+
+	if *serverURL == "" {
+		return
+	}
+
+	claims, err := getServerClaims(*serverURL, token)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error fetching claims from server: %v\n", err)
+		os.Exit(1)
+	}
+
+	printClaims(claims)
+}
+
+// printTokenInfo prints the identity contained in the cached access token, and
+// returns whether it has expired.
+func printTokenInfo(env *cmd.Environment, tokenInfo *cmd.TokenInfo) bool {
+	fmt.Fprintf(os.Stdout, "environment:    %v\n", env.Name)
+	fmt.Fprintf(os.Stdout, "email:          %v\n", tokenInfo.Email)
+	fmt.Fprintf(os.Stdout, "email verified: %v\n", tokenInfo.EmailVerified)
+	fmt.Fprintf(os.Stdout, "audience:       %v\n", tokenInfo.Aud)
+	fmt.Fprintf(os.Stdout, "scopes:         %v\n", strings.Join(strings.Fields(tokenInfo.Scope), ", "))
+
+	exp, err := strconv.ParseInt(tokenInfo.Exp, 0, 64)
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "expires:        unknown\n")
+		return false
+	}
+
+	expiry := time.Unix(exp, 0)
+	if time.Now().After(expiry) {
+		fmt.Fprintf(os.Stdout, "expires:        %v (expired)\n", expiry.Format(time.RFC3339))
+		return true
+	}
+	fmt.Fprintf(os.Stdout, "expires:        %v (in %v)\n", expiry.Format(time.RFC3339), time.Until(expiry).Round(time.Second))
+	return false
+}
+
+// getServerClaims returns the claims that the server resolves for the given
+// access token.
+func getServerClaims(serverURL string, token string) (*claimsResponse, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(serverURL, "/")+"/whoami", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request error: %s", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request error: %s", response.Status)
+	}
+
+	var claims claimsResponse
+	if err := json.NewDecoder(response.Body).Decode(&claims); err != nil {
+		return nil, fmt.Errorf("error decoding claims response: %s", err)
+	}
+
+	return &claims, nil
+}
+
+// printClaims prints the system and namespace roles resolved by the server.
+func printClaims(claims *claimsResponse) {
+	fmt.Fprintf(os.Stdout, "system role:    %v\n", claims.System)
+	fmt.Fprintf(os.Stdout, "namespace roles:\n")
+
+	namespaces := make([]string, 0, len(claims.Namespaces))
+	for ns := range claims.Namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	for _, ns := range namespaces {
+		name := ns
+		if name == "" {
+			name = "(cluster)"
+		}
+		fmt.Fprintf(os.Stdout, "  %-30v %v\n", name, claims.Namespaces[ns])
+	}
+}
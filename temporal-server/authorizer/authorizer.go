@@ -68,17 +68,15 @@ func (a *authorizer) Authorize(_ context.Context, claims *authorization.Claims,
 		return decisionAllow, nil
 	}
 
-	requiredRole := authorization.RoleWriter
-	if authorization.IsReadOnlyGlobalAPI(apiName) || authorization.IsReadOnlyNamespaceAPI(apiName) {
+	if requiredRole(apiName) == authorization.RoleReader {
 		a.logger.Info(fmt.Sprintf("allowing access to read-only API %s", apiName))
-		requiredRole = authorization.RoleReader
 	}
 
-	if claims.System >= requiredRole {
+	if claims.System >= requiredRole(apiName) {
 		return decisionAllow, nil
 	}
 
-	if claims.Namespaces[target.Namespace] >= requiredRole {
+	if defaultDecision(claims, apiName, target.Namespace) {
 		a.logger.Info(fmt.Sprintf("allowing access to %s on namespace %s", apiName, target.Namespace))
 		return decisionAllow, nil
 	}
@@ -88,6 +86,50 @@ func (a *authorizer) Authorize(_ context.Context, claims *authorization.Claims,
 	return decisionDeny, nil
 }
 
+// requiredRole returns the role required to call the given API: RoleReader for
+// read-only APIs and RoleWriter for anything else.
+func requiredRole(apiName string) authorization.Role {
+	if authorization.IsReadOnlyGlobalAPI(apiName) || authorization.IsReadOnlyNamespaceAPI(apiName) {
+		return authorization.RoleReader
+	}
+	return authorization.RoleWriter
+}
+
+// defaultDecision returns whether the given claims have the role required to
+// call the given API, either on the System or on the given namespace.
+func defaultDecision(claims *authorization.Claims, apiName string, namespace string) bool {
+	role := requiredRole(apiName)
+	return claims.System >= role || NamespaceRole(claims.Namespaces, namespace) >= role
+}
+
+// NamespacePatternSuffix marks a namespace grant as a pattern matching every
+// namespace starting with the part before it, e.g. "payments-*". A grant on
+// "*" matches every namespace.
+const NamespacePatternSuffix = "*"
+
+// NamespaceRole returns the role given on the given namespace by the given
+// namespace grants. An exact grant on the namespace wins over patterns, and a
+// longer pattern wins over a shorter one, so that "payments-prod-*" overrides
+// "payments-*". Patterns do not match the empty namespace.
+func NamespaceRole(namespaces map[string]authorization.Role, namespace string) authorization.Role {
+	if role, ok := namespaces[namespace]; ok || namespace == "" {
+		return role
+	}
+
+	var role authorization.Role
+	longest := -1
+	for grant, grantRole := range namespaces {
+		prefix, ok := strings.CutSuffix(grant, NamespacePatternSuffix)
+		if !ok || !strings.HasPrefix(namespace, prefix) {
+			continue
+		}
+		if len(prefix) > longest {
+			role, longest = grantRole, len(prefix)
+		}
+	}
+	return role
+}
+
 func (a *authorizer) logWarn(msg string) {
 	if a.logger != nil {
 		a.logger.Warn(msg)
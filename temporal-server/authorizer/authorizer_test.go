@@ -81,6 +81,30 @@ func TestAuthorize(t *testing.T) {
 		target:           "StartWorkflow",
 		targetNS:         "test-ns",
 		expectedDecision: authorization.DecisionAllow,
+	}, {
+		desc: "allow: caller has namespace pattern permissions, execute workflow",
+		claims: &authorization.Claims{
+			Namespaces: map[string]authorization.Role{"": authorization.RoleReader, "payments-*": authorization.RoleWriter},
+		},
+		target:           "StartWorkflow",
+		targetNS:         "payments-prod",
+		expectedDecision: authorization.DecisionAllow,
+	}, {
+		desc: "deny: caller has namespace pattern permissions, execute workflow in a namespace not matching it",
+		claims: &authorization.Claims{
+			Namespaces: map[string]authorization.Role{"": authorization.RoleReader, "payments-*": authorization.RoleWriter},
+		},
+		target:           "StartWorkflow",
+		targetNS:         "billing-prod",
+		expectedDecision: authorization.DecisionDeny,
+	}, {
+		desc: "deny: caller has read access on a more specific pattern, execute workflow",
+		claims: &authorization.Claims{
+			Namespaces: map[string]authorization.Role{"payments-*": authorization.RoleWriter, "payments-prod-*": authorization.RoleReader},
+		},
+		target:           "StartWorkflow",
+		targetNS:         "payments-prod-eu",
+		expectedDecision: authorization.DecisionDeny,
 	}}
 
 	for _, test := range tests {
@@ -100,3 +124,35 @@ func TestAuthorize(t *testing.T) {
 		})
 	}
 }
+
+func TestNamespaceRole(t *testing.T) {
+	c := qt.New(t)
+
+	namespaces := map[string]authorization.Role{
+		"":                   authorization.RoleReader,
+		"*":                  authorization.RoleReader,
+		"payments-*":         authorization.RoleWriter,
+		"payments-prod-*":    authorization.RoleReader,
+		"payments-prod-eu":   authorization.RoleAdmin,
+		"payments-prod-us-*": authorization.RoleWriter,
+	}
+
+	tests := []struct {
+		namespace    string
+		expectedRole authorization.Role
+	}{
+		{"", authorization.RoleReader},
+		{"billing", authorization.RoleReader},
+		{"payments-", authorization.RoleWriter},
+		{"payments-dev", authorization.RoleWriter},
+		{"payments-prod-ap", authorization.RoleReader},
+		{"payments-prod-eu", authorization.RoleAdmin},
+		{"payments-prod-us-east", authorization.RoleWriter},
+	}
+
+	for _, test := range tests {
+		c.Assert(authorizer.NamespaceRole(namespaces, test.namespace), qt.Equals, test.expectedRole, qt.Commentf("namespace %q", test.namespace))
+	}
+
+	c.Assert(authorizer.NamespaceRole(map[string]authorization.Role{"*": authorization.RoleWriter}, ""), qt.Equals, authorization.Role(0))
+}
@@ -0,0 +1,299 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// The fail modes of the circuit breaker.
+const (
+	// FailModeDeny fails requests while the circuit is open.
+	FailModeDeny = "deny"
+	// FailModeCached serves the last results fetched for a user while the
+	// circuit is open, as long as they are not older than the grace period.
+	FailModeCached = "cached"
+)
+
+// Default values of the circuit breaker settings.
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+	defaultGracePeriod      = 5 * time.Minute
+)
+
+// ErrCircuitOpen is returned by the CircuitBreaker while the circuit is open
+// and no cached result can be served instead.
+var ErrCircuitOpen = errors.New("OpenFGA circuit breaker is open")
+
+// CircuitBreakerConfig holds the configuration of the circuit breaker around
+// the calls made to OpenFGA.
+type CircuitBreakerConfig struct {
+	// Enabled enables the circuit breaker.
+	Enabled bool `yaml:"enabled"`
+	// FailureThreshold is the number of consecutive failed calls after which
+	// the circuit opens. Defaults to 5.
+	FailureThreshold int `yaml:"failureThreshold"`
+	// OpenTimeout is how long the circuit stays open before a single call is
+	// let through to probe whether OpenFGA has recovered. Defaults to 30s.
+	OpenTimeout time.Duration `yaml:"openTimeout"`
+	// FailMode is either FailModeDeny (the default) or FailModeCached.
+	FailMode string `yaml:"failMode"`
+	// GracePeriod is how long the results fetched for a user can be served
+	// in FailModeCached after they were fetched. Defaults to 5m.
+	GracePeriod time.Duration `yaml:"gracePeriod"`
+}
+
+// withDefaults returns the config with the unset settings set to their
+// default values.
+func (c CircuitBreakerConfig) withDefaults() CircuitBreakerConfig {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = defaultFailureThreshold
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = defaultOpenTimeout
+	}
+	if c.FailMode == "" {
+		c.FailMode = FailModeDeny
+	}
+	if c.GracePeriod <= 0 {
+		c.GracePeriod = defaultGracePeriod
+	}
+	return c
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// cachedResult is a result of the wrapped NamespaceAccessProvider along with
+// the time it was fetched.
+type cachedResult[T any] struct {
+	value     T
+	fetchedAt time.Time
+}
+
+// CircuitBreaker is a NamespaceAccessProvider which stops calling the
+// NamespaceAccessProvider it wraps after consecutive failures, so that an
+// OpenFGA outage fails requests fast instead of making each of them wait for
+// OpenFGA.
+//
+// Once FailureThreshold consecutive calls fail, the circuit opens. While it is
+// open, calls fail with ErrCircuitOpen or, in FailModeCached, return the last
+// result fetched for the same user if it is recent enough. After OpenTimeout,
+// the circuit is half-open: the next call is let through, and the circuit
+// closes if it succeeds or opens again if it fails.
+type CircuitBreaker struct {
+	provider NamespaceAccessProvider
+	cfg      CircuitBreakerConfig
+	logger   *zap.Logger
+
+	mu       sync.Mutex
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+	prunedAt time.Time
+	groups   map[string]cachedResult[[]string]
+	access   map[string]cachedResult[[]NamespaceAccess]
+}
+
+// NewCircuitBreaker returns a CircuitBreaker wrapping the given
+// NamespaceAccessProvider.
+func NewCircuitBreaker(provider NamespaceAccessProvider, cfg CircuitBreakerConfig, logger *zap.Logger) *CircuitBreaker {
+	return &CircuitBreaker{
+		provider: provider,
+		cfg:      cfg.withDefaults(),
+		logger:   logger,
+		groups:   make(map[string]cachedResult[[]string]),
+		access:   make(map[string]cachedResult[[]NamespaceAccess]),
+	}
+}
+
+// GetUserGroups implements NamespaceAccessProvider.GetUserGroups.
+func (b *CircuitBreaker) GetUserGroups(ctx context.Context, email string) ([]string, error) {
+	return callProvider(ctx, b, b.groups, email, func() ([]string, error) {
+		return b.provider.GetUserGroups(ctx, email)
+	})
+}
+
+// GetNamespaceAccessInformation implements
+// NamespaceAccessProvider.GetNamespaceAccessInformation.
+func (b *CircuitBreaker) GetNamespaceAccessInformation(ctx context.Context, email string, groups []string) ([]NamespaceAccess, error) {
+	key := email + "\x00" + strings.Join(groups, ",")
+	return callProvider(ctx, b, b.access, key, func() ([]NamespaceAccess, error) {
+		return b.provider.GetNamespaceAccessInformation(ctx, email, groups)
+	})
+}
+
+// IsUserBlocked implements BlockedUserChecker.IsUserBlocked, if the wrapped
+// NamespaceAccessProvider implements it. Otherwise, no user is blocked. Its
+// results are never cached, even in FailModeCached, so that blocking a user
+// takes effect immediately: while the circuit is open, it fails with
+// ErrCircuitOpen, denying every request.
+func (b *CircuitBreaker) IsUserBlocked(ctx context.Context, email string) (bool, error) {
+	checker, ok := b.provider.(BlockedUserChecker)
+	if !ok {
+		return false, nil
+	}
+	return callProvider(ctx, b, nil, email, func() (bool, error) {
+		return checker.IsUserBlocked(ctx, email)
+	})
+}
+
+// callProvider calls fn if the circuit allows it, and records its result in
+// cache under key, unless cache is nil. If the circuit is open or fn fails,
+// it falls back to the cached result. Calls failing because ctx was canceled, e.g. by a client
+// which went away, are not counted as failures of OpenFGA.
+func callProvider[T any](ctx context.Context, b *CircuitBreaker, cache map[string]cachedResult[T], key string, fn func() (T, error)) (T, error) {
+	probe, err := b.allow()
+	if err != nil {
+		return fallback(b, cache, key, err)
+	}
+
+	value, err := fn()
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled)) {
+		b.release(probe)
+		return value, err
+	}
+	b.record(err, probe)
+	if err != nil {
+		return fallback(b, cache, key, err)
+	}
+
+	if b.cfg.FailMode == FailModeCached && cache != nil {
+		b.mu.Lock()
+		b.prune()
+		cache[key] = cachedResult[T]{value: value, fetchedAt: time.Now()}
+		b.mu.Unlock()
+	}
+	return value, nil
+}
+
+// prune removes the cached results older than the grace period, which can no
+// longer be served. To keep storing results cheap, the caches are only swept
+// once per grace period, so results are kept at most twice as long. It must be
+// called with b.mu held.
+func (b *CircuitBreaker) prune() {
+	if time.Since(b.prunedAt) < b.cfg.GracePeriod {
+		return
+	}
+	b.prunedAt = time.Now()
+	pruneCache(b.groups, b.cfg.GracePeriod)
+	pruneCache(b.access, b.cfg.GracePeriod)
+}
+
+func pruneCache[T any](cache map[string]cachedResult[T], maxAge time.Duration) {
+	for key, cached := range cache {
+		if time.Since(cached.fetchedAt) > maxAge {
+			delete(cache, key)
+		}
+	}
+}
+
+// fallback returns the result cached under key in FailModeCached, if it is
+// not older than the grace period, or err otherwise.
+func fallback[T any](b *CircuitBreaker, cache map[string]cachedResult[T], key string, err error) (T, error) {
+	var zero T
+	if b.cfg.FailMode != FailModeCached {
+		return zero, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cached, ok := cache[key]
+	if !ok {
+		return zero, err
+	}
+	if age := time.Since(cached.fetchedAt); age > b.cfg.GracePeriod {
+		delete(cache, key)
+		return zero, err
+	}
+
+	b.logWarn(fmt.Sprintf("serving cached OpenFGA result fetched at %s: %v", cached.fetchedAt.Format(time.RFC3339), err))
+	return cached.value, nil
+}
+
+// allow returns whether a call can be made to the wrapped provider, and
+// whether that call is the probe of a half-open circuit. If no call can be
+// made, it returns ErrCircuitOpen.
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.cfg.OpenTimeout {
+			return false, ErrCircuitOpen
+		}
+		b.state = circuitHalfOpen
+		b.probing = true
+		b.logInfo("OpenFGA circuit breaker is half-open, probing OpenFGA")
+		return true, nil
+	case circuitHalfOpen:
+		if b.probing {
+			return false, ErrCircuitOpen
+		}
+		b.probing = true
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// release ends a call whose result says nothing about OpenFGA, letting
+// another call probe a half-open circuit.
+func (b *CircuitBreaker) release(probe bool) {
+	if probe {
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+	}
+}
+
+// record updates the state of the circuit with the result of a call.
+func (b *CircuitBreaker) record(err error, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.probing = false
+	}
+
+	if err == nil {
+		if b.state != circuitClosed {
+			b.logInfo("OpenFGA circuit breaker is closed")
+		}
+		b.state = circuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if probe || (b.state == circuitClosed && b.failures >= b.cfg.FailureThreshold) {
+		b.state = circuitOpen
+		b.openedAt = time.Now()
+		b.logWarn(fmt.Sprintf("OpenFGA circuit breaker is open after %d consecutive failures: %v", b.failures, err))
+	}
+}
+
+func (b *CircuitBreaker) logInfo(msg string) {
+	if b.logger != nil {
+		b.logger.Info(msg)
+	}
+}
+
+func (b *CircuitBreaker) logWarn(msg string) {
+	if b.logger != nil {
+		b.logger.Warn(msg)
+	}
+}
@@ -0,0 +1,184 @@
+package authorizer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	mock "github.com/canonical/charmed-temporal-image/temporal-server/authorizer/mocks"
+	gomock "github.com/golang/mock/gomock"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestCircuitBreakerDeny(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(t)
+	np := mock.NewMockNamespaceAccessProvider(ctrl)
+	ctx := context.Background()
+	errUnavailable := errors.New("connection refused")
+
+	b := authorizer.NewCircuitBreaker(np, authorizer.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+	}, nil)
+
+	// The circuit opens after 2 consecutive failures.
+	np.EXPECT().GetUserGroups(ctx, "user@example.com").Return(nil, errUnavailable).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err := b.GetUserGroups(ctx, "user@example.com")
+		c.Assert(err, qt.Equals, errUnavailable)
+	}
+
+	// Calls fail fast while it is open.
+	_, err := b.GetUserGroups(ctx, "user@example.com")
+	c.Assert(err, qt.Equals, authorizer.ErrCircuitOpen)
+
+	// A failed probe opens it again.
+	time.Sleep(60 * time.Millisecond)
+	np.EXPECT().GetUserGroups(ctx, "user@example.com").Return(nil, errUnavailable)
+	_, err = b.GetUserGroups(ctx, "user@example.com")
+	c.Assert(err, qt.Equals, errUnavailable)
+	_, err = b.GetUserGroups(ctx, "user@example.com")
+	c.Assert(err, qt.Equals, authorizer.ErrCircuitOpen)
+
+	// A successful probe closes it.
+	time.Sleep(60 * time.Millisecond)
+	np.EXPECT().GetUserGroups(ctx, "user@example.com").Return([]string{"group1"}, nil).Times(2)
+	for i := 0; i < 2; i++ {
+		groups, err := b.GetUserGroups(ctx, "user@example.com")
+		c.Assert(err, qt.IsNil)
+		c.Assert(groups, qt.DeepEquals, []string{"group1"})
+	}
+}
+
+func TestCircuitBreakerCached(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(t)
+	np := mock.NewMockNamespaceAccessProvider(ctrl)
+	ctx := context.Background()
+	errUnavailable := errors.New("connection refused")
+	access := []authorizer.NamespaceAccess{{Namespace: "foobar", Relation: "writer"}}
+
+	b := authorizer.NewCircuitBreaker(np, authorizer.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 1,
+		OpenTimeout:      time.Hour,
+		FailMode:         authorizer.FailModeCached,
+		GracePeriod:      50 * time.Millisecond,
+	}, nil)
+
+	np.EXPECT().GetNamespaceAccessInformation(ctx, "user@example.com", []string{"group1"}).Return(access, nil)
+	got, err := b.GetNamespaceAccessInformation(ctx, "user@example.com", []string{"group1"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(got, qt.DeepEquals, access)
+
+	// The last known good result is served when the call fails and while the
+	// circuit is open.
+	np.EXPECT().GetNamespaceAccessInformation(ctx, "user@example.com", []string{"group1"}).Return(nil, errUnavailable)
+	for i := 0; i < 2; i++ {
+		got, err = b.GetNamespaceAccessInformation(ctx, "user@example.com", []string{"group1"})
+		c.Assert(err, qt.IsNil)
+		c.Assert(got, qt.DeepEquals, access)
+	}
+
+	// Nothing is cached for other users or groups.
+	_, err = b.GetNamespaceAccessInformation(ctx, "user@example.com", []string{"group2"})
+	c.Assert(err, qt.Equals, authorizer.ErrCircuitOpen)
+
+	// Nor once the grace period is over.
+	time.Sleep(60 * time.Millisecond)
+	_, err = b.GetNamespaceAccessInformation(ctx, "user@example.com", []string{"group1"})
+	c.Assert(err, qt.Equals, authorizer.ErrCircuitOpen)
+}
+
+func TestCircuitBreakerPrunesCache(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(t)
+	np := mock.NewMockNamespaceAccessProvider(ctrl)
+	ctx := context.Background()
+
+	b := authorizer.NewCircuitBreaker(np, authorizer.CircuitBreakerConfig{
+		Enabled:     true,
+		FailMode:    authorizer.FailModeCached,
+		GracePeriod: 50 * time.Millisecond,
+	}, nil)
+
+	np.EXPECT().GetUserGroups(ctx, gomock.Any()).Return([]string{"group1"}, nil).Times(3)
+	_, err := b.GetUserGroups(ctx, "alice@example.com")
+	c.Assert(err, qt.IsNil)
+	_, err = b.GetUserGroups(ctx, "bob@example.com")
+	c.Assert(err, qt.IsNil)
+	c.Assert(b.CachedResults(), qt.Equals, 2)
+
+	// Results older than the grace period are removed when a new one is
+	// stored.
+	time.Sleep(60 * time.Millisecond)
+	_, err = b.GetUserGroups(ctx, "carol@example.com")
+	c.Assert(err, qt.IsNil)
+	c.Assert(b.CachedResults(), qt.Equals, 1)
+}
+
+func TestCircuitBreakerIgnoresCanceledCalls(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(t)
+	np := mock.NewMockNamespaceAccessProvider(ctrl)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	b := authorizer.NewCircuitBreaker(np, authorizer.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 1,
+	}, nil)
+
+	// Calls canceled by the client don't open the circuit.
+	np.EXPECT().GetUserGroups(ctx, "user@example.com").Return(nil, context.Canceled).Times(2)
+	for i := 0; i < 2; i++ {
+		_, err := b.GetUserGroups(ctx, "user@example.com")
+		c.Assert(err, qt.Equals, context.Canceled)
+	}
+}
+
+// blockingProvider is a NamespaceAccessProvider which is also a
+// BlockedUserChecker, as the OpenFGA client is.
+type blockingProvider struct {
+	*mock.MockNamespaceAccessProvider
+	*mock.MockBlockedUserChecker
+}
+
+func TestCircuitBreakerDoesNotCacheBlockedUsers(t *testing.T) {
+	c := qt.New(t)
+
+	ctrl := gomock.NewController(t)
+	bc := mock.NewMockBlockedUserChecker(ctrl)
+	ctx := context.Background()
+	errUnavailable := errors.New("connection refused")
+
+	b := authorizer.NewCircuitBreaker(blockingProvider{mock.NewMockNamespaceAccessProvider(ctrl), bc}, authorizer.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 1,
+		OpenTimeout:      time.Hour,
+		FailMode:         authorizer.FailModeCached,
+	}, nil)
+
+	bc.EXPECT().IsUserBlocked(ctx, "user@example.com").Return(false, nil)
+	blocked, err := b.IsUserBlocked(ctx, "user@example.com")
+	c.Assert(err, qt.IsNil)
+	c.Assert(blocked, qt.IsFalse)
+	c.Assert(b.CachedResults(), qt.Equals, 0)
+
+	// A previous "not blocked" result is not served when OpenFGA fails, nor
+	// while the circuit is open.
+	bc.EXPECT().IsUserBlocked(ctx, "user@example.com").Return(false, errUnavailable)
+	_, err = b.IsUserBlocked(ctx, "user@example.com")
+	c.Assert(err, qt.Equals, errUnavailable)
+	_, err = b.IsUserBlocked(ctx, "user@example.com")
+	c.Assert(err, qt.Equals, authorizer.ErrCircuitOpen)
+}
@@ -34,7 +34,7 @@ import (
 	"go.uber.org/zap"
 )
 
-//go:generate mockgen -destination=mocks/groups_provider_gen.go -package=mock github.com/canonical/charmed-temporal-image/temporal-server/authorizer NamespaceAccessProvider,TokenVerifier
+//go:generate mockgen -destination=mocks/groups_provider_gen.go -package=mock github.com/canonical/charmed-temporal-image/temporal-server/authorizer NamespaceAccessProvider,TokenVerifier,BlockedUserChecker
 
 // TokenVerifier is an interface that defines the methods
 // to fetch token information and verify their validity.
@@ -69,13 +69,22 @@ type TokenClaimMapper struct {
 	// NamespaceAccessProvider is used to identify the namespaces that the user
 	// logging in via the access token has access to.
 	NamespaceAccessProvider NamespaceAccessProvider
-	// AdminGroups is a comma-separated list of groups which gives full system
-	// access to all users belonging to them.
-	AdminGroups string
-	// OpenAccessNamespaces is a comma-separated list of namespaces to which everyone
-	// with valid login credentials has access. If empty, no such namespace will be
+	// AdminGroups are groups whose members are given a role on the System,
+	// e.g. RoleWriter for full system access or RoleReader for read-only
+	// access to every namespace.
+	AdminGroups AdminGroups
+	// OpenAccessNamespaces are namespaces on which everyone with valid login
+	// credentials is given a role. If empty, no such namespace will be
 	// configured.
-	OpenAccessNamespaces string
+	OpenAccessNamespaces OpenAccessNamespaces
+	// OpenAccessEmailDomains restricts OpenAccessNamespaces to users whose
+	// email belongs to one of these domains. If empty, it is not restricted.
+	OpenAccessEmailDomains []string
+	// Grants holds the time-bound grants. If nil, no such grants are given.
+	Grants *GrantStore
+	// DenyList is checked before anything else to deny the listed subjects
+	// and tokens. If nil, nobody is denied.
+	DenyList *DenyList
 	// Logger is used for logging TokenClaimMapper operations.
 	Logger *zap.Logger
 }
@@ -86,7 +95,9 @@ var roleMap = map[string]authorization.Role{
 	"admin":  authorization.RoleAdmin,
 }
 
-func NewTokenClaimMapper(ctx context.Context, cfg *ConfigWithAuth, logger *zap.Logger) (authorization.ClaimMapper, error) {
+// NewTokenClaimMapper returns a TokenClaimMapper which verifies Google OAuth
+// access tokens and reads namespace access from the configured OpenFGA store.
+func NewTokenClaimMapper(ctx context.Context, cfg *ConfigWithAuth, logger *zap.Logger) (*TokenClaimMapper, error) {
 	client, err := ofga.NewClient(ctx, ofga.OpenFGAParams{
 		Scheme:      cfg.Auth.OFGA.APIScheme,
 		Host:        cfg.Auth.OFGA.APIHost,
@@ -98,12 +109,41 @@ func NewTokenClaimMapper(ctx context.Context, cfg *ConfigWithAuth, logger *zap.L
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to ofga client: %v", err)
 	}
+	authClient := &AuthClient{OfgaClient: client}
+	var provider NamespaceAccessProvider = authClient
+	var checker BlockedUserChecker = authClient
+	if cfg.Auth.OFGA.CircuitBreaker.Enabled {
+		breaker := NewCircuitBreaker(authClient, cfg.Auth.OFGA.CircuitBreaker, logger)
+		provider, checker = breaker, breaker
+	}
+	verifier := NewVerifier(cfg.Auth.GoogleClientID, "https://www.googleapis.com/oauth2/v3/tokeninfo", "https://www.googleapis.com/auth/userinfo.email")
+	verifier.AllowedDomains = cfg.Auth.AllowedDomains
+	verifier.AllowedHostedDomains = cfg.Auth.AllowedHostedDomains
+	verifier.UserInfoURL = "https://www.googleapis.com/oauth2/v3/userinfo"
+	verifier.AllowedServiceAccountProjects = cfg.Auth.AllowedServiceAccountProjects
+	var grants *GrantStore
+	if cfg.Auth.Grants.File != "" {
+		grants, err = NewGrantStore(ctx, cfg.Auth.Grants, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var denyList *DenyList
+	if cfg.Auth.DenyList.enabled() {
+		denyList, err = NewDenyList(ctx, cfg.Auth.DenyList, checker, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &TokenClaimMapper{
-		NamespaceAccessProvider: &AuthClient{OfgaClient: client},
-		TokenVerifier:           NewVerifier(cfg.Auth.GoogleClientID, "https://www.googleapis.com/oauth2/v3/tokeninfo", "https://www.googleapis.com/auth/userinfo.email"),
+		NamespaceAccessProvider: provider,
+		Grants:                  grants,
+		DenyList:                denyList,
+		TokenVerifier:           verifier,
 		Logger:                  logger,
 		AdminGroups:             cfg.Auth.AdminGroups,
 		OpenAccessNamespaces:    cfg.Auth.OpenAccessNamespaces,
+		OpenAccessEmailDomains:  cfg.Auth.OpenAccessEmailDomains,
 	}, nil
 }
 
@@ -112,21 +152,20 @@ func NewTokenClaimMapper(ctx context.Context, cfg *ConfigWithAuth, logger *zap.L
 // to be in the format of `Bearer <token>` where `<token>` is a valid
 // Google IAM access token.
 //
-// It then verifies the groups that the user presented in the access token belongs
-// to via OpenFGA and gives access to various Temporal namespaces according to them.
+// It then verifies the groups that the user presented in the access token
+// belongs to via OpenFGA and gives access to various Temporal namespaces
+// according to them.
 //
-// If the user belongs to any of the AdminGroups groups, they get RoleWriter on the global
-// System namespace. If the user is a member of a group in OpenFGA with some level of
-// access to a given namespace, they get that access level to the namespace. E.g.
-// If user `john` is a member of group `abc`, and group `abc` is related to namespace `example`
-// as a "writer", then user `john` will be assigned RoleWriter on namespace `example`.
-// Additionally, they get RoleReader on empty namespace in order to perform initiating
-// calls required by the SDK.
+// If the user belongs to any of the AdminGroups groups, they get the role of
+// that group, or the highest one if they belong to several, on the global
+// System namespace. Unless that role is RoleWriter or RoleAdmin, they also get
+// their namespace access. If the user is a member of a group in OpenFGA with
+// some level of access to a given namespace, they get that access level to the
+// namespace. E.g. If user `john` is a member of group `abc`, and group `abc` is
+// related to namespace `example` as a "writer", then user `john` will be
+// assigned RoleWriter on namespace `example`. Additionally, they get RoleReader
+// on empty namespace in order to perform initiating calls required by the SDK.
 func (c TokenClaimMapper) GetClaims(authInfo *authorization.AuthInfo) (*authorization.Claims, error) {
-	claims := authorization.Claims{
-		Namespaces: make(map[string]authorization.Role),
-	}
-
 	if authInfo.AuthToken == "" {
 		return nil, errors.New("no auth token provided")
 	}
@@ -136,41 +175,136 @@ func (c TokenClaimMapper) GetClaims(authInfo *authorization.AuthInfo) (*authoriz
 		return nil, errors.New("invalid token length")
 	}
 
+	email, err := c.verifiedEmail(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.resolveClaims(context.Background(), email, nil)
+}
+
+// ClaimsTrace holds the intermediate results of resolving the claims of a
+// user, as returned by IntrospectClaims.
+type ClaimsTrace struct {
+	// Email is the email of the user.
+	Email string
+	// Groups are the groups the user is a member of.
+	Groups []string
+	// MatchedAdminGroups are the AdminGroups the user is a member of.
+	MatchedAdminGroups []string
+	// NamespaceAccess holds the namespace relations of the user's groups.
+	NamespaceAccess []NamespaceAccess
+	// Claims are the resulting claims.
+	Claims *authorization.Claims
+}
+
+// IntrospectClaims resolves the claims of the user with the given email, or of
+// the user the given access token belongs to if email is empty, and returns
+// them along with the intermediate results.
+func (c TokenClaimMapper) IntrospectClaims(ctx context.Context, email string, token string) (*ClaimsTrace, error) {
+	if email == "" {
+		var err error
+		email, err = c.verifiedEmail(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	trace := &ClaimsTrace{Email: email}
+	claims, err := c.resolveClaims(ctx, email, trace)
+	if err != nil {
+		return nil, err
+	}
+
+	trace.Claims = claims
+	return trace, nil
+}
+
+// verifiedEmail verifies the given access token and returns the email it
+// belongs to. Tokens on the DenyList are denied before being verified.
+func (c TokenClaimMapper) verifiedEmail(token string) (string, error) {
+	if c.DenyList != nil {
+		if err := c.DenyList.checkToken(token); err != nil {
+			return "", err
+		}
+	}
+
 	tokenInfo, err := c.TokenVerifier.GetTokenInfo(token)
 	if err != nil {
-		return nil, c.generateError(fmt.Sprintf("error fetching access token info: %v", err))
+		return "", c.generateError(fmt.Sprintf("error fetching access token info: %v", err))
 	}
 
 	err = c.TokenVerifier.VerifyToken(tokenInfo)
 	if err != nil {
-		return nil, c.generateError(fmt.Sprintf("error validating access token: %v", err))
+		return "", c.generateError(fmt.Sprintf("error validating access token: %v", err))
+	}
+
+	return tokenInfo.Email, nil
+}
+
+// resolveClaims returns the claims of the user with the given email. If trace
+// is not nil, it is filled with the intermediate results.
+func (c TokenClaimMapper) resolveClaims(ctx context.Context, email string, trace *ClaimsTrace) (*authorization.Claims, error) {
+	if c.DenyList != nil {
+		if err := c.DenyList.checkEmail(ctx, email); err != nil {
+			if !errors.Is(err, ErrDenied) {
+				return nil, c.generateError(err.Error())
+			}
+			return nil, err
+		}
 	}
 
-	email := tokenInfo.Email
-	ctx := context.Background()
+	claims := authorization.Claims{
+		Subject:    email,
+		Namespaces: make(map[string]authorization.Role),
+	}
 
-	adminGroupsSlice := strings.Split(c.AdminGroups, ",")
 	userGroups, err := c.NamespaceAccessProvider.GetUserGroups(ctx, email)
 	if err != nil {
 		return nil, c.generateError(fmt.Sprintf("error reading group membership: %v \n", err))
 	}
+	if c.Grants != nil {
+		userGroups = c.Grants.applyGroups(email, userGroups)
+	}
 
 	// Check for admin group membership
-	for _, grp := range adminGroupsSlice {
-		if grp != "" && slices.Contains(userGroups, grp) {
-			claims.System = authorization.RoleWriter
-			return &claims, nil
+	for _, grp := range c.AdminGroups {
+		if slices.Contains(userGroups, grp.Group) {
+			claims.System = max(claims.System, grp.Role)
+			if trace != nil {
+				trace.MatchedAdminGroups = append(trace.MatchedAdminGroups, grp.Group)
+			}
 		}
 	}
+	isAdmin := claims.System >= authorization.RoleWriter
+
+	if trace != nil {
+		trace.Groups = userGroups
+	}
+
+	// Namespace access is not needed by users with write access on the
+	// System, unless it is being traced.
+	if isAdmin && trace == nil {
+		return &claims, nil
+	}
 
 	namespaceAccess, err := c.NamespaceAccessProvider.GetNamespaceAccessInformation(ctx, email, userGroups)
 	if err != nil {
 		return nil, c.generateError(fmt.Sprintf("error reading namespace access: %v \n", err))
 	}
 
-	openAccessNamespaces := strings.Split(c.OpenAccessNamespaces, ",")
-	for _, ns := range openAccessNamespaces {
-		claims.Namespaces[ns] = authorization.RoleWriter
+	if trace != nil {
+		trace.NamespaceAccess = namespaceAccess
+	}
+
+	if isAdmin {
+		return &claims, nil
+	}
+
+	if c.hasOpenAccess(email) {
+		for _, ns := range c.OpenAccessNamespaces {
+			claims.Namespaces[ns.Namespace] = ns.Role
+		}
 	}
 
 	hasNamespaces := false
@@ -184,17 +318,34 @@ func (c TokenClaimMapper) GetClaims(authInfo *authorization.AuthInfo) (*authoriz
 		}
 	}
 
+	if c.Grants != nil {
+		granted := len(claims.Namespaces)
+		c.Grants.applyNamespaces(email, userGroups, claims.Namespaces)
+		hasNamespaces = hasNamespaces || len(claims.Namespaces) > granted
+	}
+
 	if len(claims.Namespaces) > 0 {
 		claims.Namespaces[""] = authorization.RoleReader
 	}
 
-	if !hasNamespaces && c.Logger != nil {
+	if !hasNamespaces && claims.System == 0 && c.Logger != nil {
 		c.Logger.Warn(fmt.Sprintf("received request with valid token but no namespace access; groups found: %v", userGroups))
 	}
 
 	return &claims, nil
 }
 
+// hasOpenAccess returns whether the user with the given email is given access
+// to the OpenAccessNamespaces.
+func (c TokenClaimMapper) hasOpenAccess(email string) bool {
+	if len(c.OpenAccessEmailDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	return at >= 0 && containsFold(c.OpenAccessEmailDomains, email[at+1:])
+}
+
 // GetNamespaceAccessInformation returns a list of namespaces that a user with the given email
 // has access to along with the type of relation they have (One of "reader", "writer" or "admin").
 func (c *AuthClient) GetNamespaceAccessInformation(ctx context.Context, email string, groups []string) ([]NamespaceAccess, error) {
@@ -255,6 +406,21 @@ func (c *AuthClient) GetUserGroups(ctx context.Context, email string) ([]string,
 	return groups, nil
 }
 
+// IsUserBlocked returns whether the user with the given email has a blocked
+// relation to a denylist object in the OpenFGA store, e.g.
+// "user:alice@example.com blocked denylist:temporal".
+func (c *AuthClient) IsUserBlocked(ctx context.Context, email string) (bool, error) {
+	tuples, _, err := c.OfgaClient.FindMatchingTuples(ctx, ofga.Tuple{
+		Object:   &ofga.Entity{Kind: "user", ID: email},
+		Relation: "blocked",
+		Target:   &ofga.Entity{Kind: "denylist", ID: ""},
+	}, 1, "")
+	if err != nil {
+		return false, err
+	}
+	return len(tuples) > 0, nil
+}
+
 // generateError returns a new error and also logs it on the provided logger.
 func (c TokenClaimMapper) generateError(msg string) error {
 	if c.Logger != nil {
@@ -2,6 +2,9 @@ package authorizer_test
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -121,6 +124,135 @@ func TestTokenVerifier(t *testing.T) {
 	}
 }
 
+func TestTokenVerifierAllowlists(t *testing.T) {
+	c := qt.New(t)
+
+	newToken := func(email string, hd string, azp string) *authorizer.TokenInfo {
+		return &authorizer.TokenInfo{
+			Exp:           fmt.Sprint(time.Now().Add(time.Hour).Unix()),
+			EmailVerified: "true",
+			Email:         email,
+			Scope:         "https://www.googleapis.com/auth/userinfo.email",
+			Azp:           azp,
+			HD:            hd,
+		}
+	}
+
+	tests := []struct {
+		desc string
+		// Inputs
+		allowedDomains                []string
+		allowedHostedDomains          []string
+		allowedServiceAccountProjects []string
+		token                         *authorizer.TokenInfo
+		// Outputs
+		expectedErr string
+	}{{
+		desc:           "success: email domain allowed",
+		allowedDomains: []string{"example.org", "Example.com"},
+		token:          newToken("user@example.com", "", "client_id"),
+	}, {
+		desc:           "error: email domain not allowed",
+		allowedDomains: []string{"example.org"},
+		token:          newToken("user@example.com", "", "client_id"),
+		expectedErr:    "token email domain not allowed",
+	}, {
+		desc:           "error: email subdomain not allowed",
+		allowedDomains: []string{"example.com"},
+		token:          newToken("user@evil.example.com", "", "client_id"),
+		expectedErr:    "token email domain not allowed",
+	}, {
+		desc:                 "success: hosted domain allowed",
+		allowedHostedDomains: []string{"example.com"},
+		token:                newToken("user@example.com", "example.com", "client_id"),
+	}, {
+		desc:                 "error: no hosted domain",
+		allowedHostedDomains: []string{"example.com"},
+		token:                newToken("user@gmail.com", "", "client_id"),
+		expectedErr:          "token hosted domain not allowed",
+	}, {
+		desc:                 "error: user account with allowed domains and another client id",
+		allowedDomains:       []string{"example.com"},
+		allowedHostedDomains: []string{"example.com"},
+		token:                newToken("user@example.com", "example.com", "badwolf_client_id"),
+		expectedErr:          "incorrect token client id",
+	}, {
+		desc:                          "success: service account project allowed",
+		allowedDomains:                []string{"example.com"},
+		allowedHostedDomains:          []string{"example.com"},
+		allowedServiceAccountProjects: []string{"project-id"},
+		token:                         newToken("service-account@project-id.iam.gserviceaccount.com", "", "123"),
+	}, {
+		desc:                          "error: service account project not allowed",
+		allowedServiceAccountProjects: []string{"project-id"},
+		token:                         newToken("service-account@other-project.iam.gserviceaccount.com", "", "123"),
+		expectedErr:                   "token service account project not allowed",
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		c.Run(test.desc, func(c *qt.C) {
+			c.Parallel()
+
+			tv := authorizer.NewVerifier("client_id", "https://www.googleapis.com/oauth2/v3/tokeninfo", "https://www.googleapis.com/auth/userinfo.email")
+			tv.AllowedDomains = test.allowedDomains
+			tv.AllowedHostedDomains = test.allowedHostedDomains
+			tv.AllowedServiceAccountProjects = test.allowedServiceAccountProjects
+
+			err := tv.VerifyToken(test.token)
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+		})
+	}
+}
+
+func TestTokenVerifierHostedDomain(t *testing.T) {
+	c := qt.New(t)
+
+	var userInfoCalls atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tokeninfo", func(w http.ResponseWriter, r *http.Request) {
+		email := "user@example.com"
+		if r.Header.Get("Authorization") == "Bearer sa-token" {
+			email = "sa@project-id.iam.gserviceaccount.com"
+		}
+		// The tokeninfo of an access token has no hd field.
+		fmt.Fprintf(w, `{"email": %q, "email_verified": "true"}`, email)
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		userInfoCalls.Add(1)
+		c.Check(r.Header.Get("Authorization"), qt.Equals, "Bearer user-token")
+		fmt.Fprint(w, `{"email": "user@example.com", "hd": "example.com"}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tv := authorizer.NewVerifier("client_id", server.URL+"/tokeninfo", "")
+	tv.UserInfoURL = server.URL + "/userinfo"
+
+	// The userinfo endpoint is only called when hosted domains are checked.
+	info, err := tv.GetTokenInfo("user-token")
+	c.Assert(err, qt.IsNil)
+	c.Assert(info.HD, qt.Equals, "")
+	c.Assert(userInfoCalls.Load(), qt.Equals, int32(0))
+
+	tv.AllowedHostedDomains = []string{"example.com"}
+	info, err = tv.GetTokenInfo("user-token")
+	c.Assert(err, qt.IsNil)
+	c.Assert(info.HD, qt.Equals, "example.com")
+	c.Assert(userInfoCalls.Load(), qt.Equals, int32(1))
+
+	// Service accounts have no hosted domain.
+	info, err = tv.GetTokenInfo("sa-token")
+	c.Assert(err, qt.IsNil)
+	c.Assert(info.Email, qt.Equals, "sa@project-id.iam.gserviceaccount.com")
+	c.Assert(userInfoCalls.Load(), qt.Equals, int32(1))
+}
+
 func TestGetClaims(t *testing.T) {
 	c := qt.New(t)
 
@@ -134,9 +266,11 @@ func TestGetClaims(t *testing.T) {
 	tests := []struct {
 		desc string
 		// Inputs
-		authInfo          *authorization.AuthInfo
-		adminGroups       string
-		setupExpectations func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call
+		authInfo               *authorization.AuthInfo
+		adminGroups            authorizer.AdminGroups
+		openAccessNamespaces   authorizer.OpenAccessNamespaces
+		openAccessEmailDomains []string
+		setupExpectations      func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call
 		// Outputs
 		expectedClaims *authorization.Claims
 		expectedErr    string
@@ -145,7 +279,7 @@ func TestGetClaims(t *testing.T) {
 		authInfo: &authorization.AuthInfo{
 			AuthToken: validAuthToken,
 		},
-		adminGroups: "group1",
+		adminGroups: authorizer.AdminGroups{{Group: "group1", Role: authorization.RoleWriter}},
 		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call {
 			return []*gomock.Call{
 				tv.EXPECT().GetTokenInfo(gomock.Any()).Return(validToken, nil),
@@ -154,6 +288,7 @@ func TestGetClaims(t *testing.T) {
 			}
 		},
 		expectedClaims: &authorization.Claims{
+			Subject:    "user@example.com",
 			System:     authorization.RoleWriter,
 			Namespaces: map[string]authorization.Role{},
 		},
@@ -162,7 +297,7 @@ func TestGetClaims(t *testing.T) {
 		authInfo: &authorization.AuthInfo{
 			AuthToken: validAuthToken,
 		},
-		adminGroups: "group1",
+		adminGroups: authorizer.AdminGroups{{Group: "group1", Role: authorization.RoleWriter}},
 		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call {
 			return []*gomock.Call{
 				tv.EXPECT().GetTokenInfo(gomock.Any()).Return(validToken, nil),
@@ -172,14 +307,15 @@ func TestGetClaims(t *testing.T) {
 			}
 		},
 		expectedClaims: &authorization.Claims{
-			Namespaces: map[string]authorization.Role{"": authorization.RoleReader},
+			Subject:    "user@example.com",
+			Namespaces: map[string]authorization.Role{},
 		},
 	}, {
 		desc: "success: authInfo contains valid token and user has access to namespace",
 		authInfo: &authorization.AuthInfo{
 			AuthToken: validAuthToken,
 		},
-		adminGroups: "group1",
+		adminGroups: authorizer.AdminGroups{{Group: "group1", Role: authorization.RoleWriter}},
 		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call {
 			return []*gomock.Call{
 				tv.EXPECT().GetTokenInfo(gomock.Any()).Return(validToken, nil),
@@ -189,10 +325,98 @@ func TestGetClaims(t *testing.T) {
 			}
 		},
 		expectedClaims: &authorization.Claims{
+			Subject:    "user@example.com",
 			Namespaces: map[string]authorization.Role{"": authorization.RoleReader, "foobar": authorization.RoleWriter},
 		},
-	},
-	}
+	}, {
+		desc: "success: user belongs to several admin groups",
+		authInfo: &authorization.AuthInfo{
+			AuthToken: validAuthToken,
+		},
+		adminGroups: authorizer.AdminGroups{
+			{Group: "temporal-readers", Role: authorization.RoleReader},
+			{Group: "temporal-admins", Role: authorization.RoleAdmin},
+		},
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call {
+			return []*gomock.Call{
+				tv.EXPECT().GetTokenInfo(gomock.Any()).Return(validToken, nil),
+				tv.EXPECT().VerifyToken(gomock.Any()).Return(nil),
+				np.EXPECT().GetUserGroups(gomock.Any(), gomock.Any()).Return([]string{"temporal-readers", "temporal-admins"}, nil),
+			}
+		},
+		expectedClaims: &authorization.Claims{
+			Subject:    "user@example.com",
+			System:     authorization.RoleAdmin,
+			Namespaces: map[string]authorization.Role{},
+		},
+	}, {
+		desc: "success: user belongs to a read-only admin group and has access to namespace",
+		authInfo: &authorization.AuthInfo{
+			AuthToken: validAuthToken,
+		},
+		adminGroups: authorizer.AdminGroups{
+			{Group: "temporal-readers", Role: authorization.RoleReader},
+			{Group: "temporal-admins", Role: authorization.RoleAdmin},
+		},
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call {
+			return []*gomock.Call{
+				tv.EXPECT().GetTokenInfo(gomock.Any()).Return(validToken, nil),
+				tv.EXPECT().VerifyToken(gomock.Any()).Return(nil),
+				np.EXPECT().GetUserGroups(gomock.Any(), gomock.Any()).Return([]string{"temporal-readers"}, nil),
+				np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), gomock.Any(), gomock.Any()).Return([]authorizer.NamespaceAccess{{Namespace: "foobar", Relation: "writer"}}, nil),
+			}
+		},
+		expectedClaims: &authorization.Claims{
+			Subject:    "user@example.com",
+			System:     authorization.RoleReader,
+			Namespaces: map[string]authorization.Role{"": authorization.RoleReader, "foobar": authorization.RoleWriter},
+		},
+	}, {
+		desc: "success: user is given the roles of open access namespaces",
+		authInfo: &authorization.AuthInfo{
+			AuthToken: validAuthToken,
+		},
+		openAccessNamespaces: authorizer.OpenAccessNamespaces{
+			{Namespace: "sandbox", Role: authorization.RoleWriter},
+			{Namespace: "shared-readonly", Role: authorization.RoleReader},
+		},
+		openAccessEmailDomains: []string{"example.org", "@Example.com"},
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call {
+			return []*gomock.Call{
+				tv.EXPECT().GetTokenInfo(gomock.Any()).Return(validToken, nil),
+				tv.EXPECT().VerifyToken(gomock.Any()).Return(nil),
+				np.EXPECT().GetUserGroups(gomock.Any(), gomock.Any()).Return([]string{}, nil),
+				np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), gomock.Any(), gomock.Any()).Return([]authorizer.NamespaceAccess{}, nil),
+			}
+		},
+		expectedClaims: &authorization.Claims{
+			Subject: "user@example.com",
+			Namespaces: map[string]authorization.Role{
+				"":                authorization.RoleReader,
+				"sandbox":         authorization.RoleWriter,
+				"shared-readonly": authorization.RoleReader,
+			},
+		},
+	}, {
+		desc: "success: user outside of the open access email domains is not given open access",
+		authInfo: &authorization.AuthInfo{
+			AuthToken: validAuthToken,
+		},
+		openAccessNamespaces:   authorizer.OpenAccessNamespaces{{Namespace: "sandbox", Role: authorization.RoleWriter}},
+		openAccessEmailDomains: []string{"ourcompany.com"},
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) []*gomock.Call {
+			return []*gomock.Call{
+				tv.EXPECT().GetTokenInfo(gomock.Any()).Return(validToken, nil),
+				tv.EXPECT().VerifyToken(gomock.Any()).Return(nil),
+				np.EXPECT().GetUserGroups(gomock.Any(), gomock.Any()).Return([]string{}, nil),
+				np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), gomock.Any(), gomock.Any()).Return([]authorizer.NamespaceAccess{}, nil),
+			}
+		},
+		expectedClaims: &authorization.Claims{
+			Subject:    "user@example.com",
+			Namespaces: map[string]authorization.Role{},
+		},
+	}}
 
 	for _, test := range tests {
 		test := test
@@ -213,6 +437,8 @@ func TestGetClaims(t *testing.T) {
 				TokenVerifier:           tv,
 				NamespaceAccessProvider: np,
 				AdminGroups:             test.adminGroups,
+				OpenAccessNamespaces:    test.openAccessNamespaces,
+				OpenAccessEmailDomains:  test.openAccessEmailDomains,
 			}
 			claims, err := cm.GetClaims(test.authInfo)
 			c.Assert(claims, qt.DeepEquals, test.expectedClaims)
@@ -2,8 +2,11 @@ package authorizer
 
 import (
 	"fmt"
+	"strings"
 
+	"go.temporal.io/server/common/authorization"
 	"go.temporal.io/server/common/config"
+	"gopkg.in/yaml.v3"
 )
 
 // ConfigWithAuth is a wrapper over Temporal Server's config.Config which also
@@ -11,18 +14,209 @@ import (
 type ConfigWithAuth struct {
 	*config.Config `yaml:",inline"`
 
-	Auth Auth `yaml:"auth"`
+	Auth Auth       `yaml:"auth"`
+	HTTP HTTPConfig `yaml:"http"`
+}
+
+// HTTPConfig holds the configuration of the optional HTTP server which is
+// started alongside the Temporal services to serve the health endpoints.
+type HTTPConfig struct {
+	// ListenAddress is the host:port on which the HTTP server listens. If
+	// empty, the HTTP server is not started.
+	ListenAddress string `yaml:"listenAddress"`
+	// Introspection configures the HTTP server of the introspection
+	// endpoints.
+	Introspection IntrospectionConfig `yaml:"introspection"`
+}
+
+// IntrospectionConfig holds the configuration of the optional HTTP server of
+// the introspection endpoints. As they receive access tokens, they are served
+// separately from the health endpoints, which are meant for probes and served
+// over plain HTTP.
+type IntrospectionConfig struct {
+	// ListenAddress is the host:port on which the HTTP server listens. If
+	// empty, the HTTP server is not started.
+	ListenAddress string `yaml:"listenAddress"`
+	// CertFile and KeyFile are the paths of the TLS certificate and key of
+	// the HTTP server. If empty, it serves plain HTTP, which must not be
+	// exposed outside the pod.
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
 }
 
 // Auth contains all the configuration and secrets required to perform
 // authentication and authorization functionalities in the Temporal Server
 // service.
 type Auth struct {
-	Enabled              bool                `yaml:"enabled"`
-	OFGA                 AuthorizationConfig `yaml:"ofga"`
-	AdminGroups          string              `yaml:"adminGroups"`
-	OpenAccessNamespaces string              `yaml:"openAccessNamespaces"`
-	GoogleClientID       string              `yaml:"googleClientID"`
+	Enabled              bool                 `yaml:"enabled"`
+	OFGA                 AuthorizationConfig  `yaml:"ofga"`
+	AdminGroups          AdminGroups          `yaml:"adminGroups"`
+	OpenAccessNamespaces OpenAccessNamespaces `yaml:"openAccessNamespaces"`
+	// OpenAccessEmailDomains restricts the access given by
+	// OpenAccessNamespaces to users whose email belongs to one of these
+	// domains. If empty, every authenticated user is given access.
+	OpenAccessEmailDomains StringList `yaml:"openAccessEmailDomains"`
+	GoogleClientID         string     `yaml:"googleClientID"`
+	// AllowedDomains restricts user accounts to those whose email belongs to
+	// one of these domains. If empty, it is not restricted.
+	AllowedDomains StringList `yaml:"allowedDomains"`
+	// AllowedHostedDomains restricts user accounts to those of one of these
+	// Google Workspace domains. If empty, it is not restricted.
+	AllowedHostedDomains StringList `yaml:"allowedHostedDomains"`
+	// AllowedServiceAccountProjects restricts service accounts to those of
+	// one of these Google Cloud projects. If empty, it is not restricted.
+	AllowedServiceAccountProjects StringList `yaml:"allowedServiceAccountProjects"`
+	// Policy configures the authorization of requests by a policy instead of
+	// the built-in rules.
+	Policy PolicyConfig `yaml:"policy"`
+	// Grants configures time-bound and break glass grants.
+	Grants GrantsConfig `yaml:"grants"`
+	// RateLimit configures the rate limits of each identity.
+	RateLimit RateLimitConfig `yaml:"rateLimit"`
+	// Identity configures the stamping of the subject of the caller on the
+	// identity of requests.
+	Identity IdentityConfig `yaml:"identity"`
+	// DenyList configures the subjects and tokens which are denied access.
+	DenyList DenyListConfig `yaml:"denyList"`
+}
+
+// StringList is a list of strings which is either a comma-separated string or
+// a sequence of strings in YAML. Empty entries are ignored.
+type StringList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *StringList) UnmarshalYAML(value *yaml.Node) error {
+	var entries []string
+	switch value.Kind {
+	case yaml.ScalarNode:
+		var s string
+		if err := value.Decode(&s); err != nil {
+			return err
+		}
+		entries = strings.Split(s, ",")
+	default:
+		if err := value.Decode(&entries); err != nil {
+			return err
+		}
+	}
+
+	*l = nil
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			*l = append(*l, entry)
+		}
+	}
+	return nil
+}
+
+// OpenAccessNamespace is a namespace on which every authenticated user is
+// given Role.
+type OpenAccessNamespace struct {
+	Namespace string
+	Role      authorization.Role
+}
+
+// OpenAccessNamespaces is a list of OpenAccessNamespace. In YAML, it is a
+// list of role grants, e.g. `sandbox:writer,shared-readonly:reader`, where the
+// role defaults to "writer". See decodeRoleGrants.
+type OpenAccessNamespaces []OpenAccessNamespace
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (n *OpenAccessNamespaces) UnmarshalYAML(value *yaml.Node) error {
+	grants, err := decodeRoleGrants(value, "open access namespace", "namespace")
+	if err != nil {
+		return err
+	}
+
+	*n = nil
+	for _, grant := range grants {
+		*n = append(*n, OpenAccessNamespace{Namespace: grant.name, Role: grant.role})
+	}
+	return nil
+}
+
+// AdminGroup is a group whose members are given Role on the System.
+type AdminGroup struct {
+	Group string
+	Role  authorization.Role
+}
+
+// AdminGroups is a list of AdminGroup. In YAML, it is a list of role grants,
+// e.g. `temporal-admins:admin,temporal-readers:reader`, where the role
+// defaults to "writer". See decodeRoleGrants.
+type AdminGroups []AdminGroup
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (g *AdminGroups) UnmarshalYAML(value *yaml.Node) error {
+	grants, err := decodeRoleGrants(value, "admin group", "group")
+	if err != nil {
+		return err
+	}
+
+	*g = nil
+	for _, grant := range grants {
+		*g = append(*g, AdminGroup{Group: grant.name, Role: grant.role})
+	}
+	return nil
+}
+
+// roleGrant is a role given on, or to, name.
+type roleGrant struct {
+	name string
+	role authorization.Role
+}
+
+// decodeRoleGrants decodes a list of role grants, which is either a mapping
+// of names to roles or a StringList of entries in the format `name[:role]`.
+// Roles are one of "reader", "writer" or "admin" and default to "writer".
+// kind and noun describe the grants in errors.
+func decodeRoleGrants(value *yaml.Node, kind string, noun string) ([]roleGrant, error) {
+	var entries []string
+	if value.Kind == yaml.MappingNode {
+		var m map[string]string
+		if err := value.Decode(&m); err != nil {
+			return nil, err
+		}
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			name := value.Content[i].Value
+			entries = append(entries, name+":"+m[name])
+		}
+	} else {
+		var list StringList
+		if err := value.Decode(&list); err != nil {
+			return nil, err
+		}
+		entries = list
+	}
+
+	var grants []roleGrant
+	seen := make(map[string]bool)
+	var errs []string
+	for _, entry := range entries {
+		name, roleName, hasRole := strings.Cut(entry, ":")
+		name, roleName = strings.TrimSpace(name), strings.TrimSpace(roleName)
+		if !hasRole {
+			roleName = "writer"
+		}
+
+		role, ok := roleMap[roleName]
+		switch {
+		case name == "":
+			errs = append(errs, fmt.Sprintf("line %d: invalid %s %q: empty %s", value.Line, kind, entry, noun))
+		case !ok:
+			errs = append(errs, fmt.Sprintf("line %d: invalid %s %q: unknown role %q, must be reader, writer or admin", value.Line, kind, entry, roleName))
+		case seen[name]:
+			errs = append(errs, fmt.Sprintf("line %d: invalid %s %q: duplicate %s %q", value.Line, kind, entry, noun, name))
+		default:
+			seen[name] = true
+			grants = append(grants, roleGrant{name: name, role: role})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, &yaml.TypeError{Errors: errs}
+	}
+	return grants, nil
 }
 
 // AuthorizationConfig holds the configuration required for communicating with
@@ -44,6 +238,9 @@ type AuthorizationConfig struct {
 	// AuthModelID is the ID of the defined authorization model that is
 	// currently being used for authorization checks.
 	AuthModelID string `yaml:"authModelID"`
+	// CircuitBreaker configures the circuit breaker around the calls made to
+	// the authorization service.
+	CircuitBreaker CircuitBreakerConfig `yaml:"circuitBreaker"`
 }
 
 // LoadConfigWithAuth loads a config yaml from the given directory. The expected
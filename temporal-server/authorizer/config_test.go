@@ -0,0 +1,112 @@
+package authorizer_test
+
+import (
+	"testing"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/authorization"
+	"gopkg.in/yaml.v3"
+)
+
+func TestOpenAccessNamespaces(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		desc string
+		// Inputs
+		config string
+		// Outputs
+		expectedNamespaces authorizer.OpenAccessNamespaces
+		expectedDomains    authorizer.StringList
+		expectedErr        string
+	}{{
+		desc:   "success: no open access namespaces",
+		config: `openAccessNamespaces: ""`,
+	}, {
+		desc:   "success: comma-separated namespaces with and without roles",
+		config: "openAccessNamespaces: sandbox:writer, shared-readonly:reader,workshop,\nopenAccessEmailDomains: ourcompany.com",
+		expectedNamespaces: authorizer.OpenAccessNamespaces{
+			{Namespace: "sandbox", Role: authorization.RoleWriter},
+			{Namespace: "shared-readonly", Role: authorization.RoleReader},
+			{Namespace: "workshop", Role: authorization.RoleWriter},
+		},
+		expectedDomains: authorizer.StringList{"ourcompany.com"},
+	}, {
+		desc:   "success: list of namespaces",
+		config: "openAccessNamespaces:\n  - sandbox\n  - ops:admin\nopenAccessEmailDomains: [ourcompany.com, partner.com]",
+		expectedNamespaces: authorizer.OpenAccessNamespaces{
+			{Namespace: "sandbox", Role: authorization.RoleWriter},
+			{Namespace: "ops", Role: authorization.RoleAdmin},
+		},
+		expectedDomains: authorizer.StringList{"ourcompany.com", "partner.com"},
+	}, {
+		desc:        "error: unknown role",
+		config:      `openAccessNamespaces: sandbox:owner`,
+		expectedErr: `yaml: unmarshal errors:\n  line 1: invalid open access namespace "sandbox:owner": unknown role "owner", must be reader, writer or admin`,
+	}, {
+		desc:        "error: empty namespace and duplicate namespace",
+		config:      `openAccessNamespaces: ":reader,sandbox,sandbox:reader"`,
+		expectedErr: `yaml: unmarshal errors:\n  line 1: invalid open access namespace ":reader": empty namespace\n  line 1: invalid open access namespace "sandbox:reader": duplicate namespace "sandbox"`,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		c.Run(test.desc, func(c *qt.C) {
+			var auth authorizer.Auth
+			err := yaml.Unmarshal([]byte(test.config), &auth)
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(auth.OpenAccessNamespaces, qt.DeepEquals, test.expectedNamespaces)
+			c.Assert(auth.OpenAccessEmailDomains, qt.DeepEquals, test.expectedDomains)
+		})
+	}
+}
+
+func TestAdminGroups(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		desc string
+		// Inputs
+		config string
+		// Outputs
+		expectedGroups authorizer.AdminGroups
+		expectedErr    string
+	}{{
+		desc:           "success: groups without roles",
+		config:         `adminGroups: admins,operators`,
+		expectedGroups: authorizer.AdminGroups{{Group: "admins", Role: authorization.RoleWriter}, {Group: "operators", Role: authorization.RoleWriter}},
+	}, {
+		desc:   "success: mapping of groups to roles",
+		config: "adminGroups:\n  temporal-admins: admin\n  temporal-readers: reader",
+		expectedGroups: authorizer.AdminGroups{
+			{Group: "temporal-admins", Role: authorization.RoleAdmin},
+			{Group: "temporal-readers", Role: authorization.RoleReader},
+		},
+	}, {
+		desc:        "error: unknown role",
+		config:      "adminGroups:\n  temporal-admins: owner",
+		expectedErr: `yaml: unmarshal errors:\n  line 2: invalid admin group "temporal-admins:owner": unknown role "owner", must be reader, writer or admin`,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		c.Run(test.desc, func(c *qt.C) {
+			var auth authorizer.Auth
+			err := yaml.Unmarshal([]byte(test.config), &auth)
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(auth.AdminGroups, qt.DeepEquals, test.expectedGroups)
+		})
+	}
+}
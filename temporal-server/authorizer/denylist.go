@@ -0,0 +1,210 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultDenyListPollInterval is how often the deny list file is checked for
+// changes by default.
+const defaultDenyListPollInterval = 2 * time.Second
+
+// ErrDenied is returned by GetClaims for a subject or token on the deny list.
+var ErrDenied = errors.New("access denied by deny list")
+
+// errEmptyDenyList is returned when loading an empty deny list file, which is
+// more likely to be a file being rewritten than an intentionally empty list.
+var errEmptyDenyList = errors.New(`deny list file is empty, an empty deny list must be written as "emails: []"`)
+
+// DenyListConfig holds the configuration of the deny list.
+type DenyListConfig struct {
+	// File is the path of a YAML file holding DenyListEntries. If empty, no
+	// file is used.
+	File string `yaml:"file"`
+	// PollInterval is how often the file is checked for changes. Defaults to
+	// 2s.
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// OFGA enables denying the users with a blocked relation in OpenFGA, e.g.
+	// "user:alice@example.com blocked denylist:temporal".
+	OFGA bool `yaml:"ofga"`
+}
+
+// enabled returns whether any source of the deny list is configured.
+func (c DenyListConfig) enabled() bool {
+	return c.File != "" || c.OFGA
+}
+
+// BlockedUserChecker is an interface that defines the method to check whether
+// a user is blocked.
+type BlockedUserChecker interface {
+	IsUserBlocked(ctx context.Context, email string) (bool, error)
+}
+
+// DenyListEntries are the subjects and tokens denied by a deny list file.
+type DenyListEntries struct {
+	// Emails are the emails of the denied users and service accounts.
+	Emails []string `yaml:"emails"`
+	// TokenHashes are the hex-encoded SHA-256 hashes of the denied access
+	// tokens, as returned by TokenHash.
+	TokenHashes []string `yaml:"tokenHashes"`
+}
+
+// ParseDenyList parses DenyListEntries in YAML. Emails and hashes are
+// lower-cased.
+func ParseDenyList(data []byte) (*DenyListEntries, error) {
+	var entries DenyListEntries
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	for i, email := range entries.Emails {
+		if !strings.Contains(email, "@") {
+			return nil, fmt.Errorf("invalid email %q", email)
+		}
+		entries.Emails[i] = strings.ToLower(strings.TrimSpace(email))
+	}
+	for i, hash := range entries.TokenHashes {
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid token hash %q, must be a hex-encoded SHA-256 hash", entries.TokenHashes[i])
+		}
+		entries.TokenHashes[i] = hash
+	}
+	return &entries, nil
+}
+
+// TokenHash returns the hex-encoded SHA-256 hash of the given access token,
+// without its "Bearer " prefix, as listed in DenyListEntries.TokenHashes.
+func TokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// DenyList denies the subjects and tokens listed in a file or blocked in
+// OpenFGA. Every request of a denied subject or token is logged as an audit
+// event, as are the changes of the file.
+type DenyList struct {
+	cfg     DenyListConfig
+	entries atomic.Pointer[DenyListEntries]
+	checker BlockedUserChecker
+	logger  *zap.Logger
+}
+
+// NewDenyList returns a DenyList using the sources of the given config. If
+// cfg.OFGA is set, checker is used to find whether users are blocked on each
+// request, so that blocking a user in OpenFGA takes effect immediately. Until
+// ctx is done, the file is checked for changes every PollInterval and the
+// entries are reloaded when it changes. If the new entries are invalid or the
+// file is empty, the previous ones are kept: a file truncated while it is
+// rewritten in place must not clear the deny list. The file should still be
+// replaced atomically, by writing a new file and renaming it, so that a
+// partially written file is never read.
+func NewDenyList(ctx context.Context, cfg DenyListConfig, checker BlockedUserChecker, logger *zap.Logger) (*DenyList, error) {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultDenyListPollInterval
+	}
+	if !cfg.OFGA {
+		checker = nil
+	}
+	d := &DenyList{cfg: cfg, checker: checker, logger: logger}
+	d.entries.Store(&DenyListEntries{})
+
+	if cfg.File == "" {
+		return d, nil
+	}
+	source, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("error reading deny list: %w", err)
+	}
+	if err := d.load(source); err != nil {
+		return nil, fmt.Errorf("error loading deny list %s: %w", cfg.File, err)
+	}
+
+	go watchFile(ctx, cfg.File, source, cfg.PollInterval, d.load, logger)
+	return d, nil
+}
+
+func (d *DenyList) load(source []byte) error {
+	if len(bytes.TrimSpace(source)) == 0 {
+		return errEmptyDenyList
+	}
+	entries, err := ParseDenyList(source)
+	if err != nil {
+		return err
+	}
+	previous := d.entries.Swap(entries)
+
+	for _, email := range entries.Emails {
+		if !slices.Contains(previous.Emails, email) {
+			d.audit(fmt.Sprintf("DENY LIST: %s added to the deny list", email), zap.String("email", email))
+		}
+	}
+	for _, email := range previous.Emails {
+		if !slices.Contains(entries.Emails, email) {
+			d.audit(fmt.Sprintf("DENY LIST: %s removed from the deny list", email), zap.String("email", email))
+		}
+	}
+	for _, hash := range entries.TokenHashes {
+		if !slices.Contains(previous.TokenHashes, hash) {
+			d.audit(fmt.Sprintf("DENY LIST: token %s added to the deny list", hash), zap.String("tokenHash", hash))
+		}
+	}
+	for _, hash := range previous.TokenHashes {
+		if !slices.Contains(entries.TokenHashes, hash) {
+			d.audit(fmt.Sprintf("DENY LIST: token %s removed from the deny list", hash), zap.String("tokenHash", hash))
+		}
+	}
+	return nil
+}
+
+// checkToken returns ErrDenied if the given access token is on the deny list.
+func (d *DenyList) checkToken(token string) error {
+	hash := TokenHash(token)
+	if !slices.Contains(d.entries.Load().TokenHashes, hash) {
+		return nil
+	}
+	d.audit(fmt.Sprintf("DENY LIST: denied request with token %s", hash), zap.String("tokenHash", hash))
+	return ErrDenied
+}
+
+// checkEmail returns ErrDenied if the user with the given email is on the
+// deny list or blocked in OpenFGA.
+func (d *DenyList) checkEmail(ctx context.Context, email string) error {
+	source := ""
+	if slices.Contains(d.entries.Load().Emails, strings.ToLower(email)) {
+		source = "file"
+	} else if d.checker != nil {
+		blocked, err := d.checker.IsUserBlocked(ctx, email)
+		if err != nil {
+			return fmt.Errorf("error checking whether %s is blocked: %w", email, err)
+		}
+		if blocked {
+			source = "openfga"
+		}
+	}
+	if source == "" {
+		return nil
+	}
+
+	d.audit(fmt.Sprintf("DENY LIST: denied request of %s", email), zap.String("email", email), zap.String("source", source))
+	return ErrDenied
+}
+
+// audit logs the given message as an audit event.
+func (d *DenyList) audit(msg string, fields ...zap.Field) {
+	if d.logger != nil {
+		d.logger.Warn(msg, append([]zap.Field{zap.String("audit", "deny-list")}, fields...)...)
+	}
+}
@@ -0,0 +1,200 @@
+package authorizer_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	mock "github.com/canonical/charmed-temporal-image/temporal-server/authorizer/mocks"
+	gomock "github.com/golang/mock/gomock"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/authorization"
+)
+
+func TestParseDenyList(t *testing.T) {
+	c := qt.New(t)
+
+	hash := authorizer.TokenHash("sometoken")
+
+	tests := []struct {
+		desc string
+		// Inputs
+		denyList string
+		// Outputs
+		expectedEntries *authorizer.DenyListEntries
+		expectedErr     string
+	}{{
+		desc:     "success: emails and token hashes",
+		denyList: "emails:\n  - Alice@Example.com\ntokenHashes:\n  - " + strings.ToUpper(hash),
+		expectedEntries: &authorizer.DenyListEntries{
+			Emails:      []string{"alice@example.com"},
+			TokenHashes: []string{hash},
+		},
+	}, {
+		desc:            "success: empty",
+		denyList:        "",
+		expectedEntries: &authorizer.DenyListEntries{},
+	}, {
+		desc:        "error: invalid email",
+		denyList:    "emails: [alice]",
+		expectedErr: `invalid email "alice"`,
+	}, {
+		desc:        "error: invalid token hash",
+		denyList:    "tokenHashes: [abcd]",
+		expectedErr: `invalid token hash "abcd", must be a hex-encoded SHA-256 hash`,
+	}}
+
+	for _, test := range tests {
+		c.Run(test.desc, func(c *qt.C) {
+			entries, err := authorizer.ParseDenyList([]byte(test.denyList))
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(entries, qt.DeepEquals, test.expectedEntries)
+		})
+	}
+}
+
+func TestGetClaimsWithDenyList(t *testing.T) {
+	c := qt.New(t)
+
+	entries, err := authorizer.ParseDenyList([]byte("emails: [denied@example.com]\ntokenHashes: [" + authorizer.TokenHash("deniedtoken") + "]"))
+	c.Assert(err, qt.IsNil)
+
+	tests := []struct {
+		desc string
+		// Inputs
+		token string
+		email string
+		// Expectations
+		setupMocks func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider, bc *mock.MockBlockedUserChecker)
+		// Outputs
+		expectedErr string
+	}{{
+		desc:  "success: not denied",
+		token: "sometoken",
+		email: "user@example.com",
+		setupMocks: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider, bc *mock.MockBlockedUserChecker) {
+			tv.EXPECT().GetTokenInfo("sometoken").Return(&authorizer.TokenInfo{Email: "user@example.com"}, nil)
+			tv.EXPECT().VerifyToken(gomock.Any()).Return(nil)
+			bc.EXPECT().IsUserBlocked(gomock.Any(), "user@example.com").Return(false, nil)
+			np.EXPECT().GetUserGroups(gomock.Any(), "user@example.com").Return(nil, nil)
+			np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), "user@example.com", nil).Return(nil, nil)
+		},
+	}, {
+		desc:  "error: token denied before being verified",
+		token: "deniedtoken",
+		setupMocks: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider, bc *mock.MockBlockedUserChecker) {
+		},
+		expectedErr: "access denied by deny list",
+	}, {
+		desc:  "error: email denied by file",
+		token: "sometoken",
+		setupMocks: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider, bc *mock.MockBlockedUserChecker) {
+			tv.EXPECT().GetTokenInfo("sometoken").Return(&authorizer.TokenInfo{Email: "Denied@example.com"}, nil)
+			tv.EXPECT().VerifyToken(gomock.Any()).Return(nil)
+		},
+		expectedErr: "access denied by deny list",
+	}, {
+		desc:  "error: user blocked in OpenFGA",
+		token: "sometoken",
+		setupMocks: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider, bc *mock.MockBlockedUserChecker) {
+			tv.EXPECT().GetTokenInfo("sometoken").Return(&authorizer.TokenInfo{Email: "blocked@example.com"}, nil)
+			tv.EXPECT().VerifyToken(gomock.Any()).Return(nil)
+			bc.EXPECT().IsUserBlocked(gomock.Any(), "blocked@example.com").Return(true, nil)
+		},
+		expectedErr: "access denied by deny list",
+	}, {
+		desc:  "error: OpenFGA failure",
+		token: "sometoken",
+		setupMocks: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider, bc *mock.MockBlockedUserChecker) {
+			tv.EXPECT().GetTokenInfo("sometoken").Return(&authorizer.TokenInfo{Email: "user@example.com"}, nil)
+			tv.EXPECT().VerifyToken(gomock.Any()).Return(nil)
+			bc.EXPECT().IsUserBlocked(gomock.Any(), "user@example.com").Return(false, errors.New("connection refused"))
+		},
+		expectedErr: "error checking whether user@example.com is blocked: connection refused",
+	}}
+
+	for _, test := range tests {
+		c.Run(test.desc, func(c *qt.C) {
+			ctrl := gomock.NewController(c)
+			tv := mock.NewMockTokenVerifier(ctrl)
+			np := mock.NewMockNamespaceAccessProvider(ctrl)
+			bc := mock.NewMockBlockedUserChecker(ctrl)
+			test.setupMocks(tv, np, bc)
+
+			cm := authorizer.TokenClaimMapper{
+				TokenVerifier:           tv,
+				NamespaceAccessProvider: np,
+				DenyList:                authorizer.NewStaticDenyList(entries, bc, nil),
+			}
+			claims, err := cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer " + test.token})
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(claims.Subject, qt.Equals, test.email)
+		})
+	}
+}
+
+func TestDenyListReload(t *testing.T) {
+	c := qt.New(t)
+
+	file := filepath.Join(c.TempDir(), "denylist.yaml")
+	err := os.WriteFile(file, []byte("emails: []"), 0o644)
+	c.Assert(err, qt.IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	denyList, err := authorizer.NewDenyList(ctx, authorizer.DenyListConfig{File: file}, nil, nil)
+	c.Assert(err, qt.IsNil)
+
+	ctrl := gomock.NewController(t)
+	tv := mock.NewMockTokenVerifier(ctrl)
+	np := mock.NewMockNamespaceAccessProvider(ctrl)
+	tv.EXPECT().GetTokenInfo(gomock.Any()).Return(&authorizer.TokenInfo{Email: "user@example.com"}, nil).AnyTimes()
+	tv.EXPECT().VerifyToken(gomock.Any()).Return(nil).AnyTimes()
+	np.EXPECT().GetUserGroups(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	cm := authorizer.TokenClaimMapper{TokenVerifier: tv, NamespaceAccessProvider: np, DenyList: denyList}
+
+	_, err = cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.IsNil)
+
+	err = denyList.Load([]byte("emails: [user@example.com]"))
+	c.Assert(err, qt.IsNil)
+	_, err = cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.Equals, authorizer.ErrDenied)
+
+	// An invalid deny list is not loaded.
+	err = denyList.Load([]byte("emails: [user]"))
+	c.Assert(err, qt.ErrorMatches, `invalid email "user"`)
+	_, err = cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.Equals, authorizer.ErrDenied)
+
+	// Nor is an empty one, as read while the file is truncated and rewritten.
+	err = denyList.Load(nil)
+	c.Assert(err, qt.ErrorMatches, `deny list file is empty, .*`)
+	_, err = cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.Equals, authorizer.ErrDenied)
+
+	err = denyList.Load([]byte("emails: []"))
+	c.Assert(err, qt.IsNil)
+	_, err = cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.IsNil)
+
+	// An empty file is not accepted on startup either.
+	err = os.WriteFile(file, nil, 0o644)
+	c.Assert(err, qt.IsNil)
+	_, err = authorizer.NewDenyList(ctx, authorizer.DenyListConfig{File: file}, nil, nil)
+	c.Assert(err, qt.ErrorMatches, `error loading deny list .*: deny list file is empty, .*`)
+}
@@ -0,0 +1,49 @@
+package authorizer
+
+import (
+	"go.temporal.io/server/common/authorization"
+	"go.uber.org/zap"
+)
+
+// LoadPolicy loads the given source into a, which must have been returned by
+// NewPolicyAuthorizer, as when its file changes.
+func LoadPolicy(a authorization.Authorizer, source []byte) error {
+	return a.(*policyAuthorizer).load(source)
+}
+
+// NewStaticGrantStore returns a GrantStore holding the given grants, which
+// must have been returned by ParseGrants.
+func NewStaticGrantStore(grants []Grant, breakGlassGroups []string, logger *zap.Logger) *GrantStore {
+	s := &GrantStore{
+		cfg:    GrantsConfig{BreakGlassGroups: breakGlassGroups},
+		logger: logger,
+	}
+	s.grants.Store(&grants)
+	return s
+}
+
+// Load loads the given source into s, as when its file changes.
+func (s *GrantStore) Load(source []byte) error {
+	return s.load(source)
+}
+
+// NewStaticDenyList returns a DenyList denying the given entries, which must
+// have been returned by ParseDenyList, and the users blocked according to
+// checker if it is not nil.
+func NewStaticDenyList(entries *DenyListEntries, checker BlockedUserChecker, logger *zap.Logger) *DenyList {
+	d := &DenyList{checker: checker, logger: logger}
+	d.entries.Store(entries)
+	return d
+}
+
+// Load loads the given source into d, as when its file changes.
+func (d *DenyList) Load(source []byte) error {
+	return d.load(source)
+}
+
+// CachedResults returns the number of results cached by b.
+func (b *CircuitBreaker) CachedResults() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.groups) + len(b.access)
+}
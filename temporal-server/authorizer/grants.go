@@ -0,0 +1,211 @@
+package authorizer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/server/common/authorization"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// Default values of the grants settings.
+const (
+	defaultGrantsPollInterval    = 10 * time.Second
+	defaultMaxBreakGlassDuration = 4 * time.Hour
+)
+
+// GrantsConfig holds the configuration of the time-bound grants.
+type GrantsConfig struct {
+	// File is the path of a YAML file holding a list of Grant. If empty, no
+	// time-bound grants are given.
+	File string `yaml:"file"`
+	// PollInterval is how often the file is checked for changes. Defaults to
+	// 10s.
+	PollInterval time.Duration `yaml:"pollInterval"`
+	// BreakGlassGroups are groups whose membership can only be given by a
+	// Grant, so that it always expires, and whose use is always logged.
+	BreakGlassGroups StringList `yaml:"breakGlassGroups"`
+	// MaxBreakGlassDuration is how far in the future a membership of a break
+	// glass group may expire when the grants are loaded. Defaults to 4h.
+	MaxBreakGlassDuration time.Duration `yaml:"maxBreakGlassDuration"`
+}
+
+// Grant is an access given until ExpiresAt, either to the user with the email
+// User or to the members of Group. A Grant without Namespace makes User a
+// member of Group. Otherwise, it gives Role on Namespace, which can be a
+// pattern as in NamespaceRole.
+type Grant struct {
+	User      string    `yaml:"user"`
+	Group     string    `yaml:"group"`
+	Namespace string    `yaml:"namespace"`
+	Role      string    `yaml:"role"`
+	ExpiresAt time.Time `yaml:"expiresAt"`
+	// Reason describes why the access was given, e.g. an incident ID.
+	Reason string `yaml:"reason"`
+
+	role authorization.Role
+}
+
+// isMembership returns whether the grant makes a user a member of a group.
+func (g Grant) isMembership() bool {
+	return g.Namespace == ""
+}
+
+// ParseGrants parses a list of Grant in YAML, as of now. Memberships of the
+// given break glass groups must expire within maxBreakGlassDuration.
+func ParseGrants(data []byte, now time.Time, breakGlassGroups []string, maxBreakGlassDuration time.Duration) ([]Grant, error) {
+	var grants []Grant
+	if err := yaml.Unmarshal(data, &grants); err != nil {
+		return nil, err
+	}
+
+	for i := range grants {
+		g := &grants[i]
+		if err := g.validate(now, breakGlassGroups, maxBreakGlassDuration); err != nil {
+			return nil, fmt.Errorf("grant %d: %w", i+1, err)
+		}
+	}
+	return grants, nil
+}
+
+// validate checks the grant and parses its role.
+func (g *Grant) validate(now time.Time, breakGlassGroups []string, maxBreakGlassDuration time.Duration) error {
+	if g.ExpiresAt.IsZero() {
+		return fmt.Errorf("expiresAt must be set")
+	}
+
+	if g.isMembership() {
+		if g.User == "" || g.Group == "" {
+			return fmt.Errorf("a grant without namespace must set both user and group")
+		}
+		if g.Role != "" {
+			return fmt.Errorf("a grant without namespace cannot set a role")
+		}
+		if slices.Contains(breakGlassGroups, g.Group) && g.ExpiresAt.After(now.Add(maxBreakGlassDuration)) {
+			return fmt.Errorf("membership of break glass group %s must expire within %s", g.Group, maxBreakGlassDuration)
+		}
+		return nil
+	}
+
+	if (g.User == "") == (g.Group == "") {
+		return fmt.Errorf("a grant on namespace %s must set exactly one of user or group", g.Namespace)
+	}
+	role, ok := roleMap[g.Role]
+	if !ok {
+		return fmt.Errorf("unknown role %q, must be reader, writer or admin", g.Role)
+	}
+	g.role = role
+	return nil
+}
+
+// GrantStore holds the time-bound grants loaded from a file.
+type GrantStore struct {
+	cfg    GrantsConfig
+	grants atomic.Pointer[[]Grant]
+	logger *zap.Logger
+}
+
+// NewGrantStore returns a GrantStore holding the grants of the file of the
+// given config. Until ctx is done, the file is checked for changes every
+// PollInterval and the grants are reloaded when it changes. If the new grants
+// are invalid, the previous ones are kept.
+func NewGrantStore(ctx context.Context, cfg GrantsConfig, logger *zap.Logger) (*GrantStore, error) {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultGrantsPollInterval
+	}
+	if cfg.MaxBreakGlassDuration <= 0 {
+		cfg.MaxBreakGlassDuration = defaultMaxBreakGlassDuration
+	}
+	s := &GrantStore{cfg: cfg, logger: logger}
+
+	source, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("error reading grants: %w", err)
+	}
+	if err := s.load(source); err != nil {
+		return nil, fmt.Errorf("error loading grants %s: %w", cfg.File, err)
+	}
+
+	go watchFile(ctx, cfg.File, source, cfg.PollInterval, s.load, logger)
+	return s, nil
+}
+
+func (s *GrantStore) load(source []byte) error {
+	grants, err := ParseGrants(source, time.Now(), s.cfg.BreakGlassGroups, s.cfg.MaxBreakGlassDuration)
+	if err != nil {
+		return err
+	}
+	s.grants.Store(&grants)
+	return nil
+}
+
+// active returns the grants which have not expired yet.
+func (s *GrantStore) active(now time.Time) []Grant {
+	var active []Grant
+	for _, g := range *s.grants.Load() {
+		if now.Before(g.ExpiresAt) {
+			active = append(active, g)
+		}
+	}
+	return active
+}
+
+// applyGroups returns the groups of the user with the given email, given
+// their groups in OpenFGA. Memberships of break glass groups in OpenFGA are
+// ignored, and those given by grants are added and logged.
+func (s *GrantStore) applyGroups(email string, groups []string) []string {
+	var applied []string
+	for _, group := range groups {
+		if slices.Contains(s.cfg.BreakGlassGroups, group) {
+			s.logWarn(fmt.Sprintf("ignoring membership of %s in break glass group %s in OpenFGA, break glass access can only be given by grants", email, group))
+			continue
+		}
+		applied = append(applied, group)
+	}
+
+	for _, g := range s.active(time.Now()) {
+		if !g.isMembership() || !strings.EqualFold(g.User, email) || slices.Contains(applied, g.Group) {
+			continue
+		}
+		applied = append(applied, g.Group)
+		if slices.Contains(s.cfg.BreakGlassGroups, g.Group) && s.logger != nil {
+			s.logger.Warn(fmt.Sprintf("BREAK GLASS: %s is using break glass group %s", email, g.Group),
+				zap.String("audit", "break-glass"),
+				zap.String("email", email),
+				zap.String("group", g.Group),
+				zap.String("reason", g.Reason),
+				zap.Time("expiresAt", g.ExpiresAt),
+			)
+		}
+	}
+	return applied
+}
+
+// applyNamespaces adds to namespaces the roles given by grants to the user
+// with the given email or to the given groups. A granted role replaces a
+// lower one.
+func (s *GrantStore) applyNamespaces(email string, groups []string, namespaces map[string]authorization.Role) {
+	for _, g := range s.active(time.Now()) {
+		if g.isMembership() {
+			continue
+		}
+		if !strings.EqualFold(g.User, email) && !slices.Contains(groups, g.Group) {
+			continue
+		}
+		if g.role > namespaces[g.Namespace] {
+			namespaces[g.Namespace] = g.role
+		}
+	}
+}
+
+func (s *GrantStore) logWarn(msg string) {
+	if s.logger != nil {
+		s.logger.Warn(msg)
+	}
+}
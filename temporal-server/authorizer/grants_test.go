@@ -0,0 +1,174 @@
+package authorizer_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	mock "github.com/canonical/charmed-temporal-image/temporal-server/authorizer/mocks"
+	gomock "github.com/golang/mock/gomock"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/authorization"
+)
+
+func TestParseGrants(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		desc string
+		// Inputs
+		grants string
+		// Outputs
+		expectedErr string
+	}{{
+		desc: "success: namespace and membership grants",
+		grants: `
+- user: alice@example.com
+  namespace: payments-prod
+  role: writer
+  expiresAt: 2026-10-20T12:00:00Z
+  reason: INC-1234
+- group: sre
+  namespace: payments-*
+  role: reader
+  expiresAt: 2026-10-20T12:00:00Z
+- user: bob@example.com
+  group: break-glass
+  expiresAt: 2026-10-19T14:00:00Z
+`,
+	}, {
+		desc:        "error: no expiry",
+		grants:      "- user: alice@example.com\n  namespace: payments-prod\n  role: writer",
+		expectedErr: "grant 1: expiresAt must be set",
+	}, {
+		desc:        "error: unknown role",
+		grants:      "- user: alice@example.com\n  namespace: payments-prod\n  role: owner\n  expiresAt: 2026-10-20T12:00:00Z",
+		expectedErr: `grant 1: unknown role "owner", must be reader, writer or admin`,
+	}, {
+		desc:        "error: namespace grant to both user and group",
+		grants:      "- user: alice@example.com\n  group: sre\n  namespace: payments-prod\n  role: writer\n  expiresAt: 2026-10-20T12:00:00Z",
+		expectedErr: "grant 1: a grant on namespace payments-prod must set exactly one of user or group",
+	}, {
+		desc:        "error: membership without user",
+		grants:      "- group: sre\n  expiresAt: 2026-10-20T12:00:00Z",
+		expectedErr: "grant 1: a grant without namespace must set both user and group",
+	}, {
+		desc:        "error: break glass membership expiring too late",
+		grants:      "- user: bob@example.com\n  group: break-glass\n  expiresAt: 2026-10-20T12:00:00Z",
+		expectedErr: "grant 1: membership of break glass group break-glass must expire within 4h0m0s",
+	}}
+
+	for _, test := range tests {
+		c.Run(test.desc, func(c *qt.C) {
+			_, err := authorizer.ParseGrants([]byte(test.grants), now, []string{"break-glass"}, 4*time.Hour)
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+			} else {
+				c.Assert(err, qt.IsNil)
+			}
+		})
+	}
+}
+
+func TestGetClaimsWithGrants(t *testing.T) {
+	c := qt.New(t)
+
+	now := time.Now()
+	grants, err := authorizer.ParseGrants([]byte(fmt.Sprintf(`
+- user: user@example.com
+  namespace: payments-prod
+  role: writer
+  expiresAt: %[1]s
+- user: user@example.com
+  namespace: billing-prod
+  role: writer
+  expiresAt: %[2]s
+- group: break-glass
+  namespace: ops
+  role: admin
+  expiresAt: %[1]s
+- user: user@example.com
+  group: break-glass
+  expiresAt: %[1]s
+  reason: INC-1234
+- user: other@example.com
+  group: sre
+  expiresAt: %[1]s
+`, now.Add(time.Hour).Format(time.RFC3339), now.Add(-time.Hour).Format(time.RFC3339))), now, []string{"break-glass"}, 4*time.Hour)
+	c.Assert(err, qt.IsNil)
+
+	ctrl := gomock.NewController(t)
+	tv := mock.NewMockTokenVerifier(ctrl)
+	np := mock.NewMockNamespaceAccessProvider(ctrl)
+	tv.EXPECT().GetTokenInfo(gomock.Any()).Return(&authorizer.TokenInfo{Email: "user@example.com"}, nil)
+	tv.EXPECT().VerifyToken(gomock.Any()).Return(nil)
+	// The membership of the break glass group in OpenFGA is ignored, the one
+	// given by the grant is used instead.
+	np.EXPECT().GetUserGroups(gomock.Any(), "user@example.com").Return([]string{"group1", "break-glass"}, nil)
+	np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), "user@example.com", []string{"group1", "break-glass"}).Return([]authorizer.NamespaceAccess{
+		{Namespace: "payments-prod", Relation: "reader"},
+	}, nil)
+
+	cm := authorizer.TokenClaimMapper{
+		TokenVerifier:           tv,
+		NamespaceAccessProvider: np,
+		Grants:                  authorizer.NewStaticGrantStore(grants, []string{"break-glass"}, nil),
+	}
+	claims, err := cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims, qt.DeepEquals, &authorization.Claims{
+		Subject: "user@example.com",
+		Namespaces: map[string]authorization.Role{
+			"":              authorization.RoleReader,
+			"payments-prod": authorization.RoleWriter,
+			"ops":           authorization.RoleAdmin,
+		},
+	})
+}
+
+func TestGrantStoreReload(t *testing.T) {
+	c := qt.New(t)
+
+	file := filepath.Join(c.TempDir(), "grants.yaml")
+	expiresAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+	err := os.WriteFile(file, []byte("- user: user@example.com\n  namespace: payments-prod\n  role: reader\n  expiresAt: "+expiresAt), 0o644)
+	c.Assert(err, qt.IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store, err := authorizer.NewGrantStore(ctx, authorizer.GrantsConfig{File: file}, nil)
+	c.Assert(err, qt.IsNil)
+
+	ctrl := gomock.NewController(t)
+	tv := mock.NewMockTokenVerifier(ctrl)
+	np := mock.NewMockNamespaceAccessProvider(ctrl)
+	tv.EXPECT().GetTokenInfo(gomock.Any()).Return(&authorizer.TokenInfo{Email: "user@example.com"}, nil).AnyTimes()
+	tv.EXPECT().VerifyToken(gomock.Any()).Return(nil).AnyTimes()
+	np.EXPECT().GetUserGroups(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	cm := authorizer.TokenClaimMapper{TokenVerifier: tv, NamespaceAccessProvider: np, Grants: store}
+
+	claims, err := cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims.Namespaces["payments-prod"], qt.Equals, authorization.RoleReader)
+
+	// Invalid grants are not loaded.
+	err = store.Load([]byte("- user: user@example.com\n  namespace: payments-prod\n  role: writer"))
+	c.Assert(err, qt.Not(qt.IsNil))
+	claims, err = cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims.Namespaces["payments-prod"], qt.Equals, authorization.RoleReader)
+
+	err = store.Load([]byte("- user: user@example.com\n  namespace: payments-prod\n  role: writer\n  expiresAt: " + expiresAt))
+	c.Assert(err, qt.IsNil)
+	claims, err = cm.GetClaims(&authorization.AuthInfo{AuthToken: "Bearer sometoken"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(claims.Namespaces["payments-prod"], qt.Equals, authorization.RoleWriter)
+}
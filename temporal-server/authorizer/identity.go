@@ -0,0 +1,96 @@
+package authorizer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common/authorization"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// The modes of the identity stamper.
+const (
+	// IdentityModeOverride replaces the identity of requests with the subject
+	// of the caller.
+	IdentityModeOverride = "override"
+	// IdentityModeValidate denies requests whose identity is not the subject
+	// of the caller. Requests without identity are given the subject.
+	IdentityModeValidate = "validate"
+)
+
+// IdentityConfig holds the configuration of the identity stamper.
+type IdentityConfig struct {
+	// Mode is either IdentityModeOverride or IdentityModeValidate. If empty,
+	// the identity of requests is left as sent by the client.
+	Mode string `yaml:"mode"`
+}
+
+// IdentityStamper sets the identity field of the requests which change
+// workflows to the subject of the caller, as verified by the claim mapper, so
+// that workflow histories show who made each change instead of the identity
+// given by the client, which can be forged.
+type IdentityStamper struct {
+	cfg    IdentityConfig
+	logger *zap.Logger
+}
+
+// NewIdentityStamper returns an IdentityStamper with the given config.
+func NewIdentityStamper(cfg IdentityConfig, logger *zap.Logger) *IdentityStamper {
+	return &IdentityStamper{cfg: cfg, logger: logger}
+}
+
+// Intercept is a grpc.UnaryServerInterceptor which stamps the subject of the
+// caller on StartWorkflowExecution, SignalWorkflowExecution,
+// SignalWithStartWorkflowExecution, TerminateWorkflowExecution and
+// RequestCancelWorkflowExecution requests. It must be chained after the
+// authorization interceptor, which adds the claims of the caller to the
+// context. Requests without a Subject are left unchanged.
+func (s *IdentityStamper) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	claims, _ := ctx.Value(authorization.MappedClaims).(*authorization.Claims)
+	if s.cfg.Mode == "" || claims == nil || claims.Subject == "" {
+		return handler(ctx, req)
+	}
+
+	identity := requestIdentity(req)
+	if identity == nil {
+		return handler(ctx, req)
+	}
+
+	if s.cfg.Mode == IdentityModeValidate && *identity != "" && !strings.EqualFold(*identity, claims.Subject) {
+		apiName := shortApiName(info.FullMethod)
+		s.logWarn(fmt.Sprintf("denied access to %s, identity %q does not match %s", apiName, *identity, claims.Subject))
+		return nil, serviceerror.NewPermissionDenied(fmt.Sprintf("identity %q does not match the authenticated subject %s", *identity, claims.Subject), "")
+	}
+
+	*identity = claims.Subject
+	return handler(ctx, req)
+}
+
+// requestIdentity returns a pointer to the identity field of the given
+// request, or nil if it is not one of the requests which are stamped.
+func requestIdentity(req interface{}) *string {
+	switch r := req.(type) {
+	case *workflowservice.StartWorkflowExecutionRequest:
+		return &r.Identity
+	case *workflowservice.SignalWorkflowExecutionRequest:
+		return &r.Identity
+	case *workflowservice.SignalWithStartWorkflowExecutionRequest:
+		return &r.Identity
+	case *workflowservice.TerminateWorkflowExecutionRequest:
+		return &r.Identity
+	case *workflowservice.RequestCancelWorkflowExecutionRequest:
+		return &r.Identity
+	default:
+		return nil
+	}
+}
+
+func (s *IdentityStamper) logWarn(msg string) {
+	if s.logger != nil {
+		s.logger.Warn(msg)
+	}
+}
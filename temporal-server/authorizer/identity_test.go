@@ -0,0 +1,106 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/server/common/authorization"
+	"google.golang.org/grpc"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestIdentityStamper(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		desc             string
+		mode             string
+		subject          string
+		req              interface{}
+		expectedIdentity string
+		expectedErr      string
+	}{{
+		desc:             "override: start replaced",
+		mode:             authorizer.IdentityModeOverride,
+		subject:          "user@example.com",
+		req:              &workflowservice.StartWorkflowExecutionRequest{Identity: "admin@example.com"},
+		expectedIdentity: "user@example.com",
+	}, {
+		desc:             "override: signal with start replaced",
+		mode:             authorizer.IdentityModeOverride,
+		subject:          "user@example.com",
+		req:              &workflowservice.SignalWithStartWorkflowExecutionRequest{Identity: "1234@host"},
+		expectedIdentity: "user@example.com",
+	}, {
+		desc:             "override: no subject",
+		mode:             authorizer.IdentityModeOverride,
+		req:              &workflowservice.TerminateWorkflowExecutionRequest{Identity: "1234@host"},
+		expectedIdentity: "1234@host",
+	}, {
+		desc:             "validate: matching identity",
+		mode:             authorizer.IdentityModeValidate,
+		subject:          "user@example.com",
+		req:              &workflowservice.SignalWorkflowExecutionRequest{Identity: "User@Example.com"},
+		expectedIdentity: "user@example.com",
+	}, {
+		desc:             "validate: empty identity stamped",
+		mode:             authorizer.IdentityModeValidate,
+		subject:          "user@example.com",
+		req:              &workflowservice.RequestCancelWorkflowExecutionRequest{},
+		expectedIdentity: "user@example.com",
+	}, {
+		desc:        "validate: forged identity denied",
+		mode:        authorizer.IdentityModeValidate,
+		subject:     "user@example.com",
+		req:         &workflowservice.TerminateWorkflowExecutionRequest{Identity: "admin@example.com"},
+		expectedErr: `identity "admin@example.com" does not match the authenticated subject user@example.com`,
+	}, {
+		desc:             "disabled: identity kept",
+		subject:          "user@example.com",
+		req:              &workflowservice.StartWorkflowExecutionRequest{Identity: "1234@host"},
+		expectedIdentity: "1234@host",
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		c.Run(test.desc, func(c *qt.C) {
+			s := authorizer.NewIdentityStamper(authorizer.IdentityConfig{Mode: test.mode}, nil)
+			ctx := context.Background()
+			if test.subject != "" {
+				ctx = context.WithValue(ctx, authorization.MappedClaims, &authorization.Claims{Subject: test.subject})
+			}
+
+			called := false
+			_, err := s.Intercept(ctx, test.req, &grpc.UnaryServerInfo{FullMethod: workflowServicePrefix + "Test"},
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					called = true
+					return nil, nil
+				})
+			if test.expectedErr != "" {
+				c.Assert(err, qt.ErrorAs, new(*serviceerror.PermissionDenied))
+				c.Assert(err, qt.ErrorMatches, test.expectedErr)
+				c.Assert(called, qt.IsFalse)
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(called, qt.IsTrue)
+			c.Assert(test.req.(interface{ GetIdentity() string }).GetIdentity(), qt.Equals, test.expectedIdentity)
+		})
+	}
+
+	// Requests without an identity field are left unchanged.
+	s := authorizer.NewIdentityStamper(authorizer.IdentityConfig{Mode: authorizer.IdentityModeOverride}, nil)
+	ctx := context.WithValue(context.Background(), authorization.MappedClaims, &authorization.Claims{Subject: "user@example.com"})
+	req := &workflowservice.PollWorkflowTaskQueueRequest{Identity: "worker@host"}
+	_, err := s.Intercept(ctx, req, &grpc.UnaryServerInfo{FullMethod: workflowServicePrefix + "PollWorkflowTaskQueue"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+	c.Assert(err, qt.IsNil)
+	c.Assert(req.Identity, qt.Equals, "worker@host")
+}
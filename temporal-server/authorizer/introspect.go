@@ -0,0 +1,207 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.temporal.io/server/common/authorization"
+	"go.uber.org/zap"
+)
+
+// ClaimsResponse is the JSON representation of authorization.Claims returned
+// by the introspection endpoints.
+type ClaimsResponse struct {
+	System     string            `json:"system"`
+	Namespaces map[string]string `json:"namespaces"`
+}
+
+// NewClaimsResponse converts the given Claims into a ClaimsResponse.
+func NewClaimsResponse(claims *authorization.Claims) ClaimsResponse {
+	resp := ClaimsResponse{
+		System:     RoleName(claims.System),
+		Namespaces: make(map[string]string, len(claims.Namespaces)),
+	}
+	for ns, role := range claims.Namespaces {
+		resp.Namespaces[ns] = RoleName(role)
+	}
+	return resp
+}
+
+// RoleName returns the name of the highest role contained in the given Role,
+// as used in the OpenFGA relations, or "none" if it contains no role.
+func RoleName(role authorization.Role) string {
+	switch {
+	case role&authorization.RoleAdmin != 0:
+		return "admin"
+	case role&authorization.RoleWriter != 0:
+		return "writer"
+	case role&authorization.RoleReader != 0:
+		return "reader"
+	case role&authorization.RoleWorker != 0:
+		return "worker"
+	default:
+		return "none"
+	}
+}
+
+type whoAmIHandler struct {
+	claimMapper authorization.ClaimMapper
+	logger      *zap.Logger
+}
+
+// NewWhoAmIHandler returns an http.Handler which returns the claims that the
+// given ClaimMapper resolves for the access token in the request's
+// `Authorization` header.
+func NewWhoAmIHandler(claimMapper authorization.ClaimMapper, logger *zap.Logger) http.Handler {
+	return &whoAmIHandler{
+		claimMapper: claimMapper,
+		logger:      logger,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *whoAmIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	claims, err := h.claimMapper.GetClaims(&authorization.AuthInfo{
+		AuthToken: r.Header.Get("Authorization"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	writeJSON(w, NewClaimsResponse(claims), h.logger)
+}
+
+// introspectClaimsAPIName is the API name under which requests to the claims
+// introspection endpoint are authorized. It is neither a read-only nor a
+// namespace API, so it requires write access on the System.
+const introspectClaimsAPIName = "IntrospectClaims"
+
+// ClaimsIntrospectionRequest is the body of a request to the claims
+// introspection endpoint. Either Email or Token must be set.
+type ClaimsIntrospectionRequest struct {
+	Email string `json:"email"`
+	Token string `json:"token"`
+}
+
+// NamespaceAccessResponse is the JSON representation of NamespaceAccess.
+type NamespaceAccessResponse struct {
+	Namespace string `json:"namespace"`
+	Relation  string `json:"relation"`
+}
+
+// ClaimsIntrospectionResponse is the response of the claims introspection
+// endpoint.
+type ClaimsIntrospectionResponse struct {
+	Email              string                    `json:"email"`
+	Groups             []string                  `json:"groups"`
+	AdminGroupMatched  bool                      `json:"adminGroupMatched"`
+	MatchedAdminGroups []string                  `json:"matchedAdminGroups"`
+	NamespaceAccess    []NamespaceAccessResponse `json:"namespaceAccess"`
+	Claims             ClaimsResponse            `json:"claims"`
+}
+
+// NewClaimsIntrospectionResponse converts the given ClaimsTrace into a
+// ClaimsIntrospectionResponse.
+func NewClaimsIntrospectionResponse(trace *ClaimsTrace) ClaimsIntrospectionResponse {
+	resp := ClaimsIntrospectionResponse{
+		Email:              trace.Email,
+		Groups:             append([]string{}, trace.Groups...),
+		AdminGroupMatched:  len(trace.MatchedAdminGroups) > 0,
+		MatchedAdminGroups: append([]string{}, trace.MatchedAdminGroups...),
+		NamespaceAccess:    make([]NamespaceAccessResponse, 0, len(trace.NamespaceAccess)),
+		Claims:             NewClaimsResponse(trace.Claims),
+	}
+	for _, access := range trace.NamespaceAccess {
+		resp.NamespaceAccess = append(resp.NamespaceAccess, NamespaceAccessResponse(access))
+	}
+	return resp
+}
+
+type claimsIntrospectionHandler struct {
+	claimMapper *TokenClaimMapper
+	authorizer  authorization.Authorizer
+	logger      *zap.Logger
+}
+
+// NewClaimsIntrospectionHandler returns an http.Handler which shows how the
+// given TokenClaimMapper resolves the claims of a user, given either their
+// email or an access token.
+//
+// The caller is authenticated with the same ClaimMapper and must be allowed by
+// the given Authorizer to call the IntrospectClaims API, which requires write
+// access on the System.
+func NewClaimsIntrospectionHandler(claimMapper *TokenClaimMapper, authorizer authorization.Authorizer, logger *zap.Logger) http.Handler {
+	return &claimsIntrospectionHandler{
+		claimMapper: claimMapper,
+		authorizer:  authorizer,
+		logger:      logger,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *claimsIntrospectionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	callerClaims, err := h.claimMapper.GetClaims(&authorization.AuthInfo{
+		AuthToken: r.Header.Get("Authorization"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	result, err := h.authorizer.Authorize(r.Context(), callerClaims, &authorization.CallTarget{
+		APIName: introspectClaimsAPIName,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if result.Decision != authorization.DecisionAllow {
+		http.Error(w, "permission denied", http.StatusForbidden)
+		return
+	}
+
+	var req ClaimsIntrospectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	req.Token = strings.TrimPrefix(req.Token, "Bearer ")
+	if (req.Email == "") == (req.Token == "") {
+		http.Error(w, "exactly one of email or token must be provided", http.StatusBadRequest)
+		return
+	}
+
+	trace, err := h.claimMapper.IntrospectClaims(r.Context(), req.Email, req.Token)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if h.logger != nil {
+		h.logger.Info(fmt.Sprintf("claims of %s introspected", trace.Email))
+	}
+
+	writeJSON(w, NewClaimsIntrospectionResponse(trace), h.logger)
+}
+
+// writeJSON writes the given value as a JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}, logger *zap.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil && logger != nil {
+		logger.Error("error writing response", zap.Error(err))
+	}
+}
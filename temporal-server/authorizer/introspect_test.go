@@ -0,0 +1,279 @@
+package authorizer_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	mock "github.com/canonical/charmed-temporal-image/temporal-server/authorizer/mocks"
+	gomock "github.com/golang/mock/gomock"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/authorization"
+	"go.temporal.io/server/common/log"
+)
+
+func TestRoleName(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		role     authorization.Role
+		expected string
+	}{
+		{role: authorization.RoleUndefined, expected: "none"},
+		{role: authorization.RoleWorker, expected: "worker"},
+		{role: authorization.RoleReader, expected: "reader"},
+		{role: authorization.RoleWriter, expected: "writer"},
+		{role: authorization.RoleAdmin, expected: "admin"},
+		{role: authorization.RoleReader | authorization.RoleWriter, expected: "writer"},
+	}
+
+	for _, test := range tests {
+		c.Assert(authorizer.RoleName(test.role), qt.Equals, test.expected)
+	}
+}
+
+func TestWhoAmIHandler(t *testing.T) {
+	c := qt.New(t)
+
+	validToken := &authorizer.TokenInfo{
+		Exp:           fmt.Sprint(time.Now().Add(time.Hour).Unix()),
+		EmailVerified: "true",
+		Email:         "user@example.com",
+		Scope:         "https://www.googleapis.com/auth/userinfo.email",
+	}
+
+	tests := []struct {
+		desc string
+		// Inputs
+		method            string
+		authHeader        string
+		setupExpectations func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider)
+		// Outputs
+		expectedStatus int
+		expectedBody   *authorizer.ClaimsResponse
+	}{{
+		desc:       "success: claims of a user with namespace access",
+		method:     http.MethodGet,
+		authHeader: "Bearer sometoken",
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) {
+			tv.EXPECT().GetTokenInfo("sometoken").Return(validToken, nil)
+			tv.EXPECT().VerifyToken(validToken).Return(nil)
+			np.EXPECT().GetUserGroups(gomock.Any(), "user@example.com").Return([]string{"group1"}, nil)
+			np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), "user@example.com", []string{"group1"}).Return([]authorizer.NamespaceAccess{{Namespace: "foobar", Relation: "writer"}}, nil)
+		},
+		expectedStatus: http.StatusOK,
+		expectedBody: &authorizer.ClaimsResponse{
+			System:     "none",
+			Namespaces: map[string]string{"": "reader", "foobar": "writer"},
+		},
+	}, {
+		desc:       "error: invalid token",
+		method:     http.MethodGet,
+		authHeader: "Bearer sometoken",
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) {
+			tv.EXPECT().GetTokenInfo("sometoken").Return(nil, errors.New("invalid token"))
+		},
+		expectedStatus: http.StatusUnauthorized,
+	}, {
+		desc:           "error: no token",
+		method:         http.MethodGet,
+		expectedStatus: http.StatusUnauthorized,
+	}, {
+		desc:           "error: method not allowed",
+		method:         http.MethodPost,
+		authHeader:     "Bearer sometoken",
+		expectedStatus: http.StatusMethodNotAllowed,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		c.Run(test.desc, func(c *qt.C) {
+			ctrl := gomock.NewController(c)
+			defer ctrl.Finish()
+			tv := mock.NewMockTokenVerifier(ctrl)
+			np := mock.NewMockNamespaceAccessProvider(ctrl)
+			if test.setupExpectations != nil {
+				test.setupExpectations(tv, np)
+			}
+
+			cm := authorizer.TokenClaimMapper{
+				TokenVerifier:           tv,
+				NamespaceAccessProvider: np,
+			}
+			handler := authorizer.NewWhoAmIHandler(cm, nil)
+
+			req := httptest.NewRequest(test.method, "/whoami", nil)
+			if test.authHeader != "" {
+				req.Header.Set("Authorization", test.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			c.Assert(rec.Code, qt.Equals, test.expectedStatus)
+			if test.expectedBody != nil {
+				var body authorizer.ClaimsResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &body)
+				c.Assert(err, qt.IsNil)
+				c.Assert(&body, qt.DeepEquals, test.expectedBody)
+			}
+		})
+	}
+}
+
+func TestClaimsIntrospectionHandler(t *testing.T) {
+	c := qt.New(t)
+
+	adminToken := &authorizer.TokenInfo{
+		Exp:           fmt.Sprint(time.Now().Add(time.Hour).Unix()),
+		EmailVerified: "true",
+		Email:         "admin@example.com",
+		Scope:         "https://www.googleapis.com/auth/userinfo.email",
+	}
+	userToken := &authorizer.TokenInfo{
+		Exp:           fmt.Sprint(time.Now().Add(time.Hour).Unix()),
+		EmailVerified: "true",
+		Email:         "user@example.com",
+		Scope:         "https://www.googleapis.com/auth/userinfo.email",
+	}
+
+	expectAdminCaller := func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) {
+		tv.EXPECT().GetTokenInfo("admintoken").Return(adminToken, nil)
+		tv.EXPECT().VerifyToken(adminToken).Return(nil)
+		np.EXPECT().GetUserGroups(gomock.Any(), "admin@example.com").Return([]string{"admins"}, nil)
+	}
+	expectUserLookup := func(np *mock.MockNamespaceAccessProvider) {
+		np.EXPECT().GetUserGroups(gomock.Any(), "user@example.com").Return([]string{"group1"}, nil)
+		np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), "user@example.com", []string{"group1"}).Return([]authorizer.NamespaceAccess{{Namespace: "foobar", Relation: "reader"}}, nil)
+	}
+	userResponse := &authorizer.ClaimsIntrospectionResponse{
+		Email:              "user@example.com",
+		Groups:             []string{"group1"},
+		MatchedAdminGroups: []string{},
+		NamespaceAccess:    []authorizer.NamespaceAccessResponse{{Namespace: "foobar", Relation: "reader"}},
+		Claims: authorizer.ClaimsResponse{
+			System:     "none",
+			Namespaces: map[string]string{"": "reader", "foobar": "reader"},
+		},
+	}
+
+	tests := []struct {
+		desc string
+		// Inputs
+		authHeader        string
+		body              string
+		setupExpectations func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider)
+		// Outputs
+		expectedStatus int
+		expectedBody   *authorizer.ClaimsIntrospectionResponse
+	}{{
+		desc:       "success: admin introspects a user by email",
+		authHeader: "Bearer admintoken",
+		body:       `{"email": "user@example.com"}`,
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) {
+			expectAdminCaller(tv, np)
+			expectUserLookup(np)
+		},
+		expectedStatus: http.StatusOK,
+		expectedBody:   userResponse,
+	}, {
+		desc:       "success: admin introspects a user by token",
+		authHeader: "Bearer admintoken",
+		body:       `{"token": "Bearer usertoken"}`,
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) {
+			expectAdminCaller(tv, np)
+			tv.EXPECT().GetTokenInfo("usertoken").Return(userToken, nil)
+			tv.EXPECT().VerifyToken(userToken).Return(nil)
+			expectUserLookup(np)
+		},
+		expectedStatus: http.StatusOK,
+		expectedBody:   userResponse,
+	}, {
+		desc:       "success: admin introspects another admin",
+		authHeader: "Bearer admintoken",
+		body:       `{"email": "admin@example.com"}`,
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) {
+			expectAdminCaller(tv, np)
+			np.EXPECT().GetUserGroups(gomock.Any(), "admin@example.com").Return([]string{"admins"}, nil)
+			np.EXPECT().GetNamespaceAccessInformation(gomock.Any(), "admin@example.com", []string{"admins"}).Return(nil, nil)
+		},
+		expectedStatus: http.StatusOK,
+		expectedBody: &authorizer.ClaimsIntrospectionResponse{
+			Email:              "admin@example.com",
+			Groups:             []string{"admins"},
+			AdminGroupMatched:  true,
+			MatchedAdminGroups: []string{"admins"},
+			NamespaceAccess:    []authorizer.NamespaceAccessResponse{},
+			Claims: authorizer.ClaimsResponse{
+				System:     "writer",
+				Namespaces: map[string]string{},
+			},
+		},
+	}, {
+		desc:       "error: caller is not an admin",
+		authHeader: "Bearer usertoken",
+		body:       `{"email": "admin@example.com"}`,
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) {
+			tv.EXPECT().GetTokenInfo("usertoken").Return(userToken, nil)
+			tv.EXPECT().VerifyToken(userToken).Return(nil)
+			expectUserLookup(np)
+		},
+		expectedStatus: http.StatusForbidden,
+	}, {
+		desc:           "error: caller is not authenticated",
+		body:           `{"email": "user@example.com"}`,
+		expectedStatus: http.StatusUnauthorized,
+	}, {
+		desc:       "error: both email and token provided",
+		authHeader: "Bearer admintoken",
+		body:       `{"email": "user@example.com", "token": "usertoken"}`,
+		setupExpectations: func(tv *mock.MockTokenVerifier, np *mock.MockNamespaceAccessProvider) {
+			expectAdminCaller(tv, np)
+		},
+		expectedStatus: http.StatusBadRequest,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		c.Run(test.desc, func(c *qt.C) {
+			ctrl := gomock.NewController(c)
+			defer ctrl.Finish()
+			tv := mock.NewMockTokenVerifier(ctrl)
+			np := mock.NewMockNamespaceAccessProvider(ctrl)
+			if test.setupExpectations != nil {
+				test.setupExpectations(tv, np)
+			}
+
+			cm := &authorizer.TokenClaimMapper{
+				TokenVerifier:           tv,
+				NamespaceAccessProvider: np,
+				AdminGroups:             authorizer.AdminGroups{{Group: "admins", Role: authorization.RoleWriter}},
+			}
+			zapLogger := log.BuildZapLogger(log.Config{})
+			handler := authorizer.NewClaimsIntrospectionHandler(cm, authorizer.NewAuthorizer(zapLogger), zapLogger)
+
+			req := httptest.NewRequest(http.MethodPost, "/debug/claims", strings.NewReader(test.body))
+			if test.authHeader != "" {
+				req.Header.Set("Authorization", test.authHeader)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			c.Assert(rec.Code, qt.Equals, test.expectedStatus)
+			if test.expectedBody != nil {
+				var body authorizer.ClaimsIntrospectionResponse
+				err := json.Unmarshal(rec.Body.Bytes(), &body)
+				c.Assert(err, qt.IsNil)
+				c.Assert(&body, qt.DeepEquals, test.expectedBody)
+			}
+		})
+	}
+}
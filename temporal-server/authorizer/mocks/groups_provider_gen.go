@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/canonical/charmed-temporal-image/temporal-server/authorizer (interfaces: NamespaceAccessProvider,TokenVerifier)
+// Source: github.com/canonical/charmed-temporal-image/temporal-server/authorizer (interfaces: NamespaceAccessProvider,TokenVerifier,BlockedUserChecker)
 
 // Package mock is a generated GoMock package.
 package mock
@@ -116,3 +116,41 @@ func (mr *MockTokenVerifierMockRecorder) VerifyToken(arg0 interface{}) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyToken", reflect.TypeOf((*MockTokenVerifier)(nil).VerifyToken), arg0)
 }
+
+// MockBlockedUserChecker is a mock of BlockedUserChecker interface.
+type MockBlockedUserChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockBlockedUserCheckerMockRecorder
+}
+
+// MockBlockedUserCheckerMockRecorder is the mock recorder for MockBlockedUserChecker.
+type MockBlockedUserCheckerMockRecorder struct {
+	mock *MockBlockedUserChecker
+}
+
+// NewMockBlockedUserChecker creates a new mock instance.
+func NewMockBlockedUserChecker(ctrl *gomock.Controller) *MockBlockedUserChecker {
+	mock := &MockBlockedUserChecker{ctrl: ctrl}
+	mock.recorder = &MockBlockedUserCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBlockedUserChecker) EXPECT() *MockBlockedUserCheckerMockRecorder {
+	return m.recorder
+}
+
+// IsUserBlocked mocks base method.
+func (m *MockBlockedUserChecker) IsUserBlocked(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsUserBlocked", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsUserBlocked indicates an expected call of IsUserBlocked.
+func (mr *MockBlockedUserCheckerMockRecorder) IsUserBlocked(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsUserBlocked", reflect.TypeOf((*MockBlockedUserChecker)(nil).IsUserBlocked), arg0, arg1)
+}
@@ -0,0 +1,256 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"go.temporal.io/server/common/authorization"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultPolicyPollInterval is how often the policy file is checked for
+// changes by default.
+const defaultPolicyPollInterval = 10 * time.Second
+
+// PolicyConfig holds the configuration of the policy authorizer.
+type PolicyConfig struct {
+	// File is the path of a file holding a CEL expression which evaluates to
+	// true if a request is allowed. If set, requests are authorized by
+	// evaluating it instead of the built-in rules.
+	File string `yaml:"file"`
+	// PollInterval is how often the file is checked for changes. Defaults to
+	// 10s.
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+// PolicyInput is the input of a policy.
+type PolicyInput struct {
+	// Claims are the claims of the caller.
+	Claims *authorization.Claims
+	// APIName is the name of the API called, with or without its service
+	// prefix.
+	APIName string
+	// Namespace is the namespace the request targets, if any.
+	Namespace string
+	// Headers are the metadata headers of the request.
+	Headers map[string]string
+}
+
+// Policy is a compiled CEL policy.
+//
+// The expression can use the following variables:
+//   - claims: a map holding the "subject" of the caller, its "system" role
+//     and its "namespaces" roles, e.g. {"payments-*": "writer"}. Roles are
+//     "admin", "writer", "reader", "worker" or "none".
+//   - api: the name of the API called, e.g. "StartWorkflowExecution".
+//   - namespace: the namespace the request targets, or "".
+//   - headers: the metadata headers of the request, without the
+//     authorization headers.
+//   - readOnly: whether the API is read-only.
+//   - systemRole: the role of the caller on the System.
+//   - namespaceRole: the role of the caller on the namespace, resolving
+//     namespace patterns as the built-in rules do.
+//   - defaultDecision: whether the built-in rules allow the request.
+type Policy struct {
+	source  string
+	program cel.Program
+}
+
+// CompilePolicy compiles the given CEL expression into a Policy. The
+// expression must evaluate to a bool.
+func CompilePolicy(source string) (*Policy, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("claims", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("api", cel.StringType),
+		cel.Variable("namespace", cel.StringType),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable("readOnly", cel.BoolType),
+		cel.Variable("systemRole", cel.StringType),
+		cel.Variable("namespaceRole", cel.StringType),
+		cel.Variable("defaultDecision", cel.BoolType),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(source)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("policy must evaluate to a bool, not %s", ast.OutputType())
+	}
+
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{source: source, program: program}, nil
+}
+
+// Evaluate returns whether the policy allows a request with the given input.
+func (p *Policy) Evaluate(input PolicyInput) (bool, error) {
+	claims := input.Claims
+	if claims == nil {
+		claims = &authorization.Claims{}
+	}
+	namespaces := make(map[string]string, len(claims.Namespaces))
+	for ns, role := range claims.Namespaces {
+		namespaces[ns] = RoleName(role)
+	}
+	headers := input.Headers
+	if headers == nil {
+		headers = map[string]string{}
+	}
+	apiName := shortApiName(input.APIName)
+
+	out, _, err := p.program.Eval(map[string]interface{}{
+		"claims": map[string]interface{}{
+			"subject":    claims.Subject,
+			"system":     RoleName(claims.System),
+			"namespaces": namespaces,
+		},
+		"api":             apiName,
+		"namespace":       input.Namespace,
+		"headers":         headers,
+		"readOnly":        requiredRole(apiName) == authorization.RoleReader,
+		"systemRole":      RoleName(claims.System),
+		"namespaceRole":   RoleName(NamespaceRole(claims.Namespaces, input.Namespace)),
+		"defaultDecision": defaultDecision(claims, apiName, input.Namespace),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	allowed, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("policy evaluated to %v, not a bool", out.Value())
+	}
+	return allowed, nil
+}
+
+type policyAuthorizer struct {
+	policy atomic.Pointer[Policy]
+	logger *zap.Logger
+}
+
+// NewPolicyAuthorizer returns an authorization.Authorizer which authorizes
+// requests by evaluating the Policy in the file of the given config. Until
+// ctx is done, the file is checked for changes every PollInterval and the
+// policy is reloaded when it changes. If the new policy doesn't compile, the
+// previous one is kept.
+//
+// As with the built-in rules, requests without claims are denied and health
+// checks are allowed without evaluating the policy.
+func NewPolicyAuthorizer(ctx context.Context, cfg PolicyConfig, logger *zap.Logger) (authorization.Authorizer, error) {
+	source, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policy: %w", err)
+	}
+	policy, err := CompilePolicy(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("error compiling policy %s: %w", cfg.File, err)
+	}
+
+	a := &policyAuthorizer{logger: logger}
+	a.policy.Store(policy)
+
+	pollInterval := cfg.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPolicyPollInterval
+	}
+	go watchFile(ctx, cfg.File, source, pollInterval, a.load, logger)
+
+	return a, nil
+}
+
+func (a *policyAuthorizer) load(source []byte) error {
+	policy, err := CompilePolicy(string(source))
+	if err != nil {
+		return err
+	}
+	a.policy.Store(policy)
+	return nil
+}
+
+// Authorize implements authorization.Authorizer.
+func (a *policyAuthorizer) Authorize(ctx context.Context, claims *authorization.Claims,
+	target *authorization.CallTarget) (authorization.Result, error) {
+	apiName := shortApiName(target.APIName)
+
+	if claims == nil {
+		a.logWarn(fmt.Sprintf("denied access to %s on namespace %s, no claims provided", apiName, target.Namespace))
+		return decisionDeny, nil
+	}
+
+	if authorization.IsHealthCheckAPI(apiName) || authorization.IsHealthCheckAPI(target.APIName) {
+		return decisionAllow, nil
+	}
+
+	allowed, err := a.policy.Load().Evaluate(PolicyInput{
+		Claims:    claims,
+		APIName:   target.APIName,
+		Namespace: target.Namespace,
+		Headers:   requestHeaders(ctx),
+	})
+	if err != nil {
+		a.logError(fmt.Sprintf("denied access to %s on namespace %s, error evaluating policy: %v", apiName, target.Namespace, err))
+		return decisionDeny, nil
+	}
+
+	if !allowed {
+		a.logWarn(fmt.Sprintf("denied access to %s on namespace %s by policy", apiName, target.Namespace))
+		return decisionDeny, nil
+	}
+	return decisionAllow, nil
+}
+
+// requestHeaders returns the metadata headers of the incoming request in ctx,
+// without the authorization headers. Headers with several values are joined
+// with commas.
+func requestHeaders(ctx context.Context) map[string]string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	headers := make(map[string]string, len(md))
+	for key, values := range md {
+		if strings.HasPrefix(key, "authorization") {
+			continue
+		}
+		headers[key] = strings.Join(values, ",")
+	}
+	return headers
+}
+
+func (a *policyAuthorizer) logWarn(msg string) {
+	if a.logger != nil {
+		a.logger.Warn(msg)
+	}
+}
+
+func (a *policyAuthorizer) logError(msg string) {
+	if a.logger != nil {
+		a.logger.Error(msg)
+	}
+}
+
+// ParseRoleName returns the role with the given name, as returned by
+// RoleName.
+func ParseRoleName(name string) (authorization.Role, error) {
+	if name == "none" || name == "" {
+		return 0, nil
+	}
+	if name == "worker" {
+		return authorization.RoleWorker, nil
+	}
+	role, ok := roleMap[name]
+	if !ok {
+		return 0, errors.New("unknown role " + name)
+	}
+	return role, nil
+}
@@ -0,0 +1,121 @@
+package authorizer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/authorization"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCompilePolicy(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := authorizer.CompilePolicy(`defaultDecision && api != "TerminateWorkflowExecution"`)
+	c.Assert(err, qt.IsNil)
+
+	_, err = authorizer.CompilePolicy(`namespaceRole`)
+	c.Assert(err, qt.ErrorMatches, "policy must evaluate to a bool, not string")
+
+	_, err = authorizer.CompilePolicy(`unknown == "x"`)
+	c.Assert(err, qt.ErrorMatches, "(?s).*undeclared reference to 'unknown'.*")
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	c := qt.New(t)
+
+	policy, err := authorizer.CompilePolicy(`
+		(defaultDecision && !(api == "TerminateWorkflowExecution" && namespaceRole != "admin")) ||
+		(readOnly && "client-name" in headers && headers["client-name"] == "temporal-ui" && claims.subject.endsWith("@example.com"))
+	`)
+	c.Assert(err, qt.IsNil)
+
+	claims := &authorization.Claims{
+		Subject: "user@example.com",
+		Namespaces: map[string]authorization.Role{
+			"payments-*":    authorization.RoleWriter,
+			"payments-prod": authorization.RoleAdmin,
+		},
+	}
+
+	tests := []struct {
+		desc string
+		// Inputs
+		input authorizer.PolicyInput
+		// Outputs
+		expectedAllowed bool
+	}{{
+		desc:            "allowed by the default rules",
+		input:           authorizer.PolicyInput{Claims: claims, APIName: "/temporal.api.workflowservice.v1.WorkflowService/StartWorkflowExecution", Namespace: "payments-dev"},
+		expectedAllowed: true,
+	}, {
+		desc:  "terminate requires admin",
+		input: authorizer.PolicyInput{Claims: claims, APIName: "TerminateWorkflowExecution", Namespace: "payments-dev"},
+	}, {
+		desc:            "terminate by admin",
+		input:           authorizer.PolicyInput{Claims: claims, APIName: "TerminateWorkflowExecution", Namespace: "payments-prod"},
+		expectedAllowed: true,
+	}, {
+		desc:  "denied by the default rules",
+		input: authorizer.PolicyInput{Claims: claims, APIName: "ListWorkflowExecutions", Namespace: "billing"},
+	}, {
+		desc:            "read-only access from the UI",
+		input:           authorizer.PolicyInput{Claims: claims, APIName: "ListWorkflowExecutions", Namespace: "billing", Headers: map[string]string{"client-name": "temporal-ui"}},
+		expectedAllowed: true,
+	}}
+
+	for _, test := range tests {
+		c.Run(test.desc, func(c *qt.C) {
+			allowed, err := policy.Evaluate(test.input)
+			c.Assert(err, qt.IsNil)
+			c.Assert(allowed, qt.Equals, test.expectedAllowed)
+		})
+	}
+}
+
+func TestPolicyAuthorizer(t *testing.T) {
+	c := qt.New(t)
+
+	file := filepath.Join(c.TempDir(), "policy.cel")
+	err := os.WriteFile(file, []byte(`headers["x-team"] == "payments"`), 0o644)
+	c.Assert(err, qt.IsNil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, err := authorizer.NewPolicyAuthorizer(ctx, authorizer.PolicyConfig{File: file}, nil)
+	c.Assert(err, qt.IsNil)
+
+	claims := &authorization.Claims{}
+	target := &authorization.CallTarget{APIName: "StartWorkflowExecution", Namespace: "payments"}
+	reqCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("x-team", "payments"))
+
+	result, err := a.Authorize(reqCtx, claims, target)
+	c.Assert(err, qt.IsNil)
+	c.Assert(result.Decision, qt.Equals, authorization.DecisionAllow)
+
+	result, err = a.Authorize(reqCtx, nil, target)
+	c.Assert(err, qt.IsNil)
+	c.Assert(result.Decision, qt.Equals, authorization.DecisionDeny)
+
+	// A policy which doesn't compile is not loaded.
+	err = authorizer.LoadPolicy(a, []byte(`headers[`))
+	c.Assert(err, qt.Not(qt.IsNil))
+	result, err = a.Authorize(reqCtx, claims, target)
+	c.Assert(err, qt.IsNil)
+	c.Assert(result.Decision, qt.Equals, authorization.DecisionAllow)
+
+	// A new policy replaces the previous one.
+	err = authorizer.LoadPolicy(a, []byte(`false`))
+	c.Assert(err, qt.IsNil)
+	result, err = a.Authorize(reqCtx, claims, target)
+	c.Assert(err, qt.IsNil)
+	c.Assert(result.Decision, qt.Equals, authorization.DecisionDeny)
+
+	_, err = authorizer.NewPolicyAuthorizer(ctx, authorizer.PolicyConfig{File: filepath.Join(c.TempDir(), "missing.cel")}, nil)
+	c.Assert(err, qt.ErrorMatches, "error reading policy: .*")
+}
@@ -0,0 +1,176 @@
+package authorizer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/authorization"
+	"go.temporal.io/server/common/metrics"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+)
+
+// The API classes which are rate limited separately.
+const (
+	apiClassRead  = "read"
+	apiClassWrite = "write"
+)
+
+// The identity classes used to tag the rate limiting metrics.
+const (
+	identityClassUser           = "user"
+	identityClassServiceAccount = "service_account"
+)
+
+// The metrics recorded by the RateLimiter.
+const (
+	identityRequestsMetric    = "identity_requests"
+	identityRateLimitedMetric = "identity_rate_limited"
+)
+
+// rateLimiterIdleTimeout is how long the token bucket of an identity is kept
+// after its last request.
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+// RateLimitConfig holds the configuration of the per-identity rate limits.
+type RateLimitConfig struct {
+	// Enabled enables the rate limits.
+	Enabled bool `yaml:"enabled"`
+	// Read is the rate limit of each identity on read-only APIs.
+	Read RateLimit `yaml:"read"`
+	// Write is the rate limit of each identity on any other API.
+	Write RateLimit `yaml:"write"`
+}
+
+// RateLimit is the rate limit of a token bucket.
+type RateLimit struct {
+	// RPS is the number of requests allowed per second. If 0, requests are
+	// not limited.
+	RPS float64 `yaml:"rps"`
+	// Burst is the number of requests which can be made at once. Defaults to
+	// RPS, rounded up.
+	Burst int `yaml:"burst"`
+}
+
+// rateLimitKey identifies a token bucket.
+type rateLimitKey struct {
+	subject  string
+	apiClass string
+}
+
+type rateLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimiter limits the rate of the requests made by each identity, as given
+// by the Subject of its claims, with a token bucket per identity and API
+// class.
+type RateLimiter struct {
+	cfg            RateLimitConfig
+	metricsHandler metrics.Handler
+	logger         *zap.Logger
+
+	mu        sync.Mutex
+	limiters  map[rateLimitKey]*rateLimiterEntry
+	lastSweep time.Time
+}
+
+// NewRateLimiter returns a RateLimiter with the given config, which records
+// its metrics with the given metrics.Handler.
+func NewRateLimiter(cfg RateLimitConfig, metricsHandler metrics.Handler, logger *zap.Logger) *RateLimiter {
+	return &RateLimiter{
+		cfg:            cfg,
+		metricsHandler: metricsHandler,
+		logger:         logger,
+		limiters:       make(map[rateLimitKey]*rateLimiterEntry),
+		lastSweep:      time.Now(),
+	}
+}
+
+// Intercept is a grpc.UnaryServerInterceptor which fails requests exceeding
+// the rate limit of their identity with a ResourceExhausted error. It must be
+// chained after the authorization interceptor, which adds the claims of the
+// caller to the context. Requests without a Subject and health checks are
+// not limited.
+func (l *RateLimiter) Intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	claims, _ := ctx.Value(authorization.MappedClaims).(*authorization.Claims)
+	apiName := shortApiName(info.FullMethod)
+	if claims == nil || claims.Subject == "" || authorization.IsHealthCheckAPI(apiName) || authorization.IsHealthCheckAPI(info.FullMethod) {
+		return handler(ctx, req)
+	}
+
+	apiClass := apiClassWrite
+	if requiredRole(apiName) == authorization.RoleReader {
+		apiClass = apiClassRead
+	}
+
+	metricsHandler := l.metricsHandler.WithTags(
+		metrics.StringTag("identity_class", identityClass(claims.Subject)),
+		metrics.StringTag("api_class", apiClass),
+	)
+	metricsHandler.Counter(identityRequestsMetric).Record(1)
+
+	if !l.allow(claims.Subject, apiClass, time.Now()) {
+		metricsHandler.Counter(identityRateLimitedMetric).Record(1)
+		if l.logger != nil {
+			l.logger.Warn(fmt.Sprintf("rate limited %s request %s of %s", apiClass, apiName, claims.Subject))
+		}
+		return nil, serviceerror.NewResourceExhausted(enumspb.RESOURCE_EXHAUSTED_CAUSE_RPS_LIMIT,
+			fmt.Sprintf("%s rate limit exceeded for %s", apiClass, claims.Subject))
+	}
+
+	return handler(ctx, req)
+}
+
+// allow returns whether a request of the given API class can be made by the
+// given subject at the given time.
+func (l *RateLimiter) allow(subject string, apiClass string, now time.Time) bool {
+	limit := l.cfg.Write
+	if apiClass == apiClassRead {
+		limit = l.cfg.Read
+	}
+	if limit.RPS <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimiterIdleTimeout {
+		for key, entry := range l.limiters {
+			if now.Sub(entry.lastSeen) > rateLimiterIdleTimeout {
+				delete(l.limiters, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := rateLimitKey{subject: subject, apiClass: apiClass}
+	entry, ok := l.limiters[key]
+	if !ok {
+		burst := limit.Burst
+		if burst <= 0 {
+			burst = int(math.Ceil(limit.RPS))
+		}
+		entry = &rateLimiterEntry{limiter: rate.NewLimiter(rate.Limit(limit.RPS), burst)}
+		l.limiters[key] = entry
+	}
+	entry.lastSeen = now
+	return entry.limiter.AllowN(now, 1)
+}
+
+// identityClass returns the class of the identity with the given email.
+func identityClass(email string) string {
+	if strings.HasSuffix(email, serviceAccountSuffix) {
+		return identityClassServiceAccount
+	}
+	return identityClassUser
+}
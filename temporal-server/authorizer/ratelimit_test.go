@@ -0,0 +1,86 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/server/common/authorization"
+	"go.temporal.io/server/common/metrics/metricstest"
+	"google.golang.org/grpc"
+
+	qt "github.com/frankban/quicktest"
+)
+
+const workflowServicePrefix = "/temporal.api.workflowservice.v1.WorkflowService/"
+
+func TestRateLimiter(t *testing.T) {
+	c := qt.New(t)
+
+	metricsHandler := metricstest.NewCaptureHandler()
+	capture := metricsHandler.StartCapture()
+	l := authorizer.NewRateLimiter(authorizer.RateLimitConfig{
+		Enabled: true,
+		Read:    authorizer.RateLimit{RPS: 0.001, Burst: 2},
+		Write:   authorizer.RateLimit{RPS: 0.001},
+	}, metricsHandler, nil)
+
+	call := func(subject string, apiName string) error {
+		ctx := context.Background()
+		if subject != "" {
+			ctx = context.WithValue(ctx, authorization.MappedClaims, &authorization.Claims{Subject: subject})
+		}
+		info := &grpc.UnaryServerInfo{FullMethod: workflowServicePrefix + apiName}
+		_, err := l.Intercept(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return "ok", nil
+		})
+		return err
+	}
+
+	// The read bucket allows a burst of 2 requests.
+	c.Assert(call("user@example.com", "DescribeNamespace"), qt.IsNil)
+	c.Assert(call("user@example.com", "ListWorkflowExecutions"), qt.IsNil)
+	err := call("user@example.com", "DescribeNamespace")
+	c.Assert(err, qt.ErrorAs, new(*serviceerror.ResourceExhausted))
+
+	// The write bucket is separate, and its burst defaults to 1.
+	c.Assert(call("user@example.com", "StartWorkflowExecution"), qt.IsNil)
+	err = call("user@example.com", "SignalWorkflowExecution")
+	c.Assert(err, qt.ErrorAs, new(*serviceerror.ResourceExhausted))
+
+	// Each identity has its own buckets.
+	c.Assert(call("sa@project.iam.gserviceaccount.com", "StartWorkflowExecution"), qt.IsNil)
+
+	// Requests without a subject are not limited.
+	for i := 0; i < 3; i++ {
+		c.Assert(call("", "StartWorkflowExecution"), qt.IsNil)
+	}
+
+	limited := capture.Snapshot()["identity_rate_limited"]
+	c.Assert(limited, qt.HasLen, 2)
+	c.Assert(limited[0].Tags, qt.DeepEquals, map[string]string{"identity_class": "user", "api_class": "read"})
+	c.Assert(limited[1].Tags, qt.DeepEquals, map[string]string{"identity_class": "user", "api_class": "write"})
+
+	requests := capture.Snapshot()["identity_requests"]
+	c.Assert(requests, qt.HasLen, 6)
+	c.Assert(requests[5].Tags, qt.DeepEquals, map[string]string{"identity_class": "service_account", "api_class": "write"})
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	c := qt.New(t)
+
+	l := authorizer.NewRateLimiter(authorizer.RateLimitConfig{
+		Enabled: true,
+		Write:   authorizer.RateLimit{RPS: 0.001},
+	}, metricstest.NewCaptureHandler(), nil)
+
+	ctx := context.WithValue(context.Background(), authorization.MappedClaims, &authorization.Claims{Subject: "user@example.com"})
+	info := &grpc.UnaryServerInfo{FullMethod: workflowServicePrefix + "GetWorkflowExecutionHistory"}
+	for i := 0; i < 10; i++ {
+		_, err := l.Intercept(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		c.Assert(err, qt.IsNil)
+	}
+}
@@ -24,6 +24,16 @@ type TokenInfo struct {
 	Email         string `json:"email"`
 	EmailVerified string `json:"email_verified"`
 	AccessType    string `json:"access_type"`
+	// HD is the hosted domain of the Google Workspace account, if any. The
+	// tokeninfo endpoint doesn't return it for access tokens, so it is read
+	// from the userinfo endpoint, and only if AllowedHostedDomains is set.
+	HD string `json:"hd"`
+}
+
+// userInfo holds the part of the response of the userinfo endpoint read by
+// the Verifier.
+type userInfo struct {
+	HD string `json:"hd"`
 }
 
 // Verifier provides configuration parameters for verifying Google OAuth tokens.
@@ -31,6 +41,19 @@ type Verifier struct {
 	GoogleClientID string
 	TokenURL       string
 	RequiredScope  string
+	// UserInfoURL is the URL of the userinfo endpoint, from which the hosted
+	// domain of user accounts is read when AllowedHostedDomains is set.
+	UserInfoURL string
+	// AllowedDomains, if not empty, restricts user accounts to those whose
+	// email belongs to one of these domains.
+	AllowedDomains []string
+	// AllowedHostedDomains, if not empty, restricts user accounts to those of
+	// one of these Google Workspace domains, as given by the `hd` field of the
+	// userinfo of the token.
+	AllowedHostedDomains []string
+	// AllowedServiceAccountProjects, if not empty, restricts service accounts
+	// to those of one of these Google Cloud projects.
+	AllowedServiceAccountProjects []string
 }
 
 // NewVerifier returns a new Verifier implementation.
@@ -42,20 +65,41 @@ func NewVerifier(clientID string, tokenUrl string, requiredScope string) *Verifi
 	}
 }
 
-// GetTokenInfo fetches a given access token's information.
+// GetTokenInfo fetches a given access token's information. If
+// AllowedHostedDomains is set, the hosted domain of user accounts is fetched
+// from the userinfo endpoint.
 func (v Verifier) GetTokenInfo(accessToken string) (*TokenInfo, error) {
+	var tokenInfo TokenInfo
+	if err := getJSON(v.TokenURL, accessToken, &tokenInfo); err != nil {
+		return nil, err
+	}
+
+	if len(v.AllowedHostedDomains) > 0 && !strings.HasSuffix(tokenInfo.Email, serviceAccountSuffix) {
+		var info userInfo
+		if err := getJSON(v.UserInfoURL, accessToken, &info); err != nil {
+			return nil, fmt.Errorf("error fetching user info: %w", err)
+		}
+		tokenInfo.HD = info.HD
+	}
+
+	return &tokenInfo, nil
+}
+
+// getJSON fetches the given URL with the given access token and decodes the
+// JSON response into v.
+func getJSON(url string, accessToken string, v interface{}) error {
 	client := &http.Client{}
 
-	req, err := http.NewRequest("GET", v.TokenURL, nil)
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -64,15 +108,10 @@ func (v Verifier) GetTokenInfo(accessToken string) (*TokenInfo, error) {
 		bodyBytes, _ := ioutil.ReadAll(resp.Body)
 		bodyString := string(bodyBytes)
 
-		return nil, fmt.Errorf("request error: %s, request body: %s", resp.Status, bodyString)
-	}
-
-	var tokenInfo TokenInfo
-	if err := json.NewDecoder(resp.Body).Decode(&tokenInfo); err != nil {
-		return nil, err
+		return fmt.Errorf("request error: %s, request body: %s", resp.Status, bodyString)
 	}
 
-	return &tokenInfo, nil
+	return json.NewDecoder(resp.Body).Decode(v)
 }
 
 // VerifyToken verifies that a given TokenInfo is valid by
@@ -87,8 +126,12 @@ func (v Verifier) VerifyToken(token *TokenInfo) error {
 	expirationTime := time.Unix(intExp, 0)
 	currentTime := time.Now()
 
-	if !strings.HasSuffix(token.Email, serviceAccountSuffix) && v.GoogleClientID != "" && token.Azp != v.GoogleClientID {
-		return errors.New("incorrect token client id")
+	if strings.HasSuffix(token.Email, serviceAccountSuffix) {
+		if err := v.verifyServiceAccount(token); err != nil {
+			return err
+		}
+	} else if err := v.verifyUserAccount(token); err != nil {
+		return err
 	}
 
 	if v.RequiredScope != "" && !strings.Contains(token.Scope, v.RequiredScope) {
@@ -105,3 +148,54 @@ func (v Verifier) VerifyToken(token *TokenInfo) error {
 
 	return nil
 }
+
+// verifyUserAccount verifies that the token of a user account was issued to
+// the expected client and that the account belongs to an allowed domain.
+func (v Verifier) verifyUserAccount(token *TokenInfo) error {
+	if v.GoogleClientID != "" && token.Azp != v.GoogleClientID {
+		return errors.New("incorrect token client id")
+	}
+
+	if len(v.AllowedDomains) > 0 {
+		at := strings.LastIndex(token.Email, "@")
+		if at < 0 || !containsFold(v.AllowedDomains, token.Email[at+1:]) {
+			return errors.New("token email domain not allowed")
+		}
+	}
+
+	if len(v.AllowedHostedDomains) > 0 && !containsFold(v.AllowedHostedDomains, token.HD) {
+		return errors.New("token hosted domain not allowed")
+	}
+
+	return nil
+}
+
+// verifyServiceAccount verifies that the token of a service account belongs
+// to an allowed project. Service account tokens are not issued to the client,
+// so their client ID is not checked.
+func (v Verifier) verifyServiceAccount(token *TokenInfo) error {
+	if len(v.AllowedServiceAccountProjects) == 0 {
+		return nil
+	}
+
+	project := strings.TrimSuffix(token.Email, serviceAccountSuffix)
+	at := strings.LastIndex(project, "@")
+	if at < 0 || !containsFold(v.AllowedServiceAccountProjects, project[at+1:]) {
+		return errors.New("token service account project not allowed")
+	}
+	return nil
+}
+
+// containsFold returns whether values contains s, ignoring case and a leading
+// "@" in values.
+func containsFold(values []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimPrefix(value, "@"), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,250 @@
+package authorizer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"go.temporal.io/server/common/config"
+	"gopkg.in/validator.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem found in a config file. Line is 0 if the
+// problem cannot be attributed to a particular line.
+type ValidationError struct {
+	Line    int
+	Message string
+	// UnknownKey is set if the problem is a key which is unknown to
+	// ConfigWithAuth. Such keys are ignored by LoadConfigWithAuth.
+	UnknownKey bool
+}
+
+// Error implements error.
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// ValidateConfigWithAuth parses the given config file the same way as
+// LoadConfigWithAuth, but also reports keys which are unknown to
+// ConfigWithAuth. It returns every problem found, sorted by line.
+func ValidateConfigWithAuth(data []byte) []ValidationError {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return yamlErrors(err)
+	}
+
+	cfg := ConfigWithAuth{Config: &config.Config{}}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var errs []ValidationError
+	if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		errs = yamlErrors(err)
+		for _, err := range errs {
+			// Further checks on a partially decoded config would only add
+			// confusing errors.
+			if !err.UnknownKey {
+				return errs
+			}
+		}
+	}
+
+	if err := validator.Validate(cfg); err != nil {
+		errs = append(errs, ValidationError{Message: err.Error()})
+	}
+
+	if err := cfg.Config.Validate(); err != nil {
+		errs = append(errs, ValidationError{Message: err.Error()})
+	}
+
+	for _, err := range append(cfg.Auth.validate(), cfg.HTTP.Introspection.validate()...) {
+		err.Line = lineOf(&root, err.path...)
+		errs = append(errs, err.ValidationError)
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Line < errs[j].Line
+	})
+
+	return errs
+}
+
+// authError is a problem found in the Auth config, along with the path of the
+// key it concerns.
+type authError struct {
+	ValidationError
+	path []string
+}
+
+// validate checks that the settings needed to perform auth are present when
+// auth is enabled.
+func (a Auth) validate() []authError {
+	if !a.Enabled {
+		return nil
+	}
+
+	required := []struct {
+		value string
+		path  []string
+	}{
+		{a.OFGA.APIScheme, []string{"auth", "ofga", "apiScheme"}},
+		{a.OFGA.APIHost, []string{"auth", "ofga", "apiHost"}},
+		{a.OFGA.APIPort, []string{"auth", "ofga", "apiPort"}},
+		{a.OFGA.StoreID, []string{"auth", "ofga", "storeID"}},
+	}
+
+	var errs []authError
+	for _, r := range required {
+		if r.value == "" {
+			errs = append(errs, authError{
+				ValidationError: ValidationError{Message: fmt.Sprintf("%s must be set when auth is enabled", strings.Join(r.path, "."))},
+				path:            r.path,
+			})
+		}
+	}
+
+	if a.OFGA.APIScheme != "" && a.OFGA.APIScheme != "http" && a.OFGA.APIScheme != "https" {
+		errs = append(errs, authError{
+			ValidationError: ValidationError{Message: fmt.Sprintf("invalid value for auth.ofga.apiScheme: %q, must be http or https", a.OFGA.APIScheme)},
+			path:            []string{"auth", "ofga", "apiScheme"},
+		})
+	}
+
+	if a.Identity.Mode != "" && a.Identity.Mode != IdentityModeOverride && a.Identity.Mode != IdentityModeValidate {
+		errs = append(errs, authError{
+			ValidationError: ValidationError{Message: fmt.Sprintf("invalid value for auth.identity.mode: %q, must be %s or %s", a.Identity.Mode, IdentityModeOverride, IdentityModeValidate)},
+			path:            []string{"auth", "identity", "mode"},
+		})
+	}
+
+	errs = append(errs, a.OFGA.CircuitBreaker.validate()...)
+	return append(errs, a.RateLimit.validate()...)
+}
+
+// validate checks that the TLS certificate and key of the introspection
+// endpoints are set together.
+func (c IntrospectionConfig) validate() []authError {
+	if (c.CertFile == "") == (c.KeyFile == "") {
+		return nil
+	}
+	path := []string{"http", "introspection", "certFile"}
+	if c.KeyFile == "" {
+		path[2] = "keyFile"
+	}
+	return []authError{{
+		ValidationError: ValidationError{Message: "http.introspection.certFile and http.introspection.keyFile must be set together"},
+		path:            path,
+	}}
+}
+
+// validate checks the values of the circuit breaker settings.
+func (c CircuitBreakerConfig) validate() []authError {
+	path := []string{"auth", "ofga", "circuitBreaker"}
+	invalid := func(key string, msg string) authError {
+		return authError{
+			ValidationError: ValidationError{Message: fmt.Sprintf("invalid value for %s.%s: %s", strings.Join(path, "."), key, msg)},
+			path:            append(path, key),
+		}
+	}
+
+	var errs []authError
+	if c.FailMode != "" && c.FailMode != FailModeDeny && c.FailMode != FailModeCached {
+		errs = append(errs, invalid("failMode", fmt.Sprintf("%q, must be %s or %s", c.FailMode, FailModeDeny, FailModeCached)))
+	}
+	if c.FailureThreshold < 0 {
+		errs = append(errs, invalid("failureThreshold", "must not be negative"))
+	}
+	if c.OpenTimeout < 0 {
+		errs = append(errs, invalid("openTimeout", "must not be negative"))
+	}
+	if c.GracePeriod < 0 {
+		errs = append(errs, invalid("gracePeriod", "must not be negative"))
+	}
+	return errs
+}
+
+// validate checks the values of the rate limit settings.
+func (c RateLimitConfig) validate() []authError {
+	var errs []authError
+	for _, l := range []struct {
+		key   string
+		limit RateLimit
+	}{{"read", c.Read}, {"write", c.Write}} {
+		path := []string{"auth", "rateLimit", l.key}
+		invalid := func(field string) authError {
+			return authError{
+				ValidationError: ValidationError{Message: fmt.Sprintf("invalid value for %s.%s: must not be negative", strings.Join(path, "."), field)},
+				path:            append(path, field),
+			}
+		}
+		if l.limit.RPS < 0 {
+			errs = append(errs, invalid("rps"))
+		}
+		if l.limit.Burst < 0 {
+			errs = append(errs, invalid("burst"))
+		}
+	}
+	return errs
+}
+
+// yamlErrors converts a YAML decoding error into ValidationErrors. The
+// messages of yaml.TypeError already start with the line number.
+func yamlErrors(err error) []ValidationError {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return []ValidationError{{Message: err.Error()}}
+	}
+
+	errs := make([]ValidationError, 0, len(typeErr.Errors))
+	for _, msg := range typeErr.Errors {
+		var line int
+		if _, scanErr := fmt.Sscanf(msg, "line %d:", &line); scanErr == nil {
+			msg = strings.TrimSpace(strings.TrimPrefix(msg, fmt.Sprintf("line %d:", line)))
+		}
+		errs = append(errs, ValidationError{
+			Line:       line,
+			Message:    msg,
+			UnknownKey: strings.HasPrefix(msg, "field ") && strings.Contains(msg, " not found in type "),
+		})
+	}
+	return errs
+}
+
+// lineOf returns the line of the deepest key of the given path which is
+// present in the document, or 0 if none are.
+func lineOf(root *yaml.Node, path ...string) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line := 0
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return line
+		}
+
+		found := false
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				line = node.Content[i].Line
+				node = node.Content[i+1]
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return line
+		}
+	}
+
+	return line
+}
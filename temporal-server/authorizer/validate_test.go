@@ -0,0 +1,244 @@
+package authorizer_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+
+	qt "github.com/frankban/quicktest"
+)
+
+const validConfig = `
+log:
+  stdout: true
+  level: info
+
+auth:
+  enabled: true
+  adminGroups: admins
+  googleClientID: google_client_id
+  ofga:
+    apiScheme: http
+    apiHost: openfga
+    apiPort: 8080
+    storeID: store
+
+persistence:
+  defaultStore: default
+  visibilityStore: visibility
+  numHistoryShards: 4
+  datastores:
+    default:
+      cassandra:
+        hosts: "127.0.0.1"
+        keyspace: "temporal"
+    visibility:
+      cassandra:
+        hosts: "127.0.0.1"
+        keyspace: "temporal_visibility"
+`
+
+func TestValidateConfigWithAuth(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		desc   string
+		config string
+		// expectedErrs are the expected errors, as returned by Error().
+		expectedErrs []string
+		// expectedUnknownKeys is the number of errors about unknown keys.
+		expectedUnknownKeys int
+	}{{
+		desc:   "success: valid config",
+		config: validConfig,
+	}, {
+		// The Verifier skips the client ID check when it is empty.
+		desc:   "success: auth enabled without googleClientID",
+		config: strings.Replace(validConfig, "  googleClientID: google_client_id\n", "", 1),
+	}, {
+		desc: "error: unknown keys",
+		config: validConfig + `
+kafka:
+  tls:
+    enabled: false
+services:
+  frontend:
+    rpc:
+      grpcPort: 7233
+      grpcPrt: 7234
+`,
+		expectedErrs: []string{
+			"line 30: field kafka not found in type authorizer.ConfigWithAuth",
+			"line 37: field grpcPrt not found in type config.RPC",
+		},
+		expectedUnknownKeys: 2,
+	}, {
+		desc: "error: invalid values",
+		config: `
+auth:
+  enabled: yes please
+  ofga:
+    apiPort: [8080]
+`,
+		expectedErrs: []string{
+			"line 3: cannot unmarshal !!str `yes please` into bool",
+			"line 5: cannot unmarshal !!seq into string",
+		},
+	}, {
+		desc: "error: auth enabled without the required settings",
+		config: `
+auth:
+  enabled: true
+  ofga:
+    apiScheme: ftp
+    apiHost: openfga
+persistence:
+  defaultStore: default
+  visibilityStore: default
+  numHistoryShards: 4
+  datastores:
+    default:
+      cassandra:
+        hosts: "127.0.0.1"
+        keyspace: "temporal"
+`,
+		expectedErrs: []string{
+			"line 4: auth.ofga.apiPort must be set when auth is enabled",
+			"line 4: auth.ofga.storeID must be set when auth is enabled",
+			"line 5: invalid value for auth.ofga.apiScheme: \"ftp\", must be http or https",
+		},
+	}, {
+		desc: "error: invalid circuit breaker settings",
+		config: `
+auth:
+  enabled: true
+  googleClientID: google_client_id
+  ofga:
+    apiScheme: http
+    apiHost: openfga
+    apiPort: 8080
+    storeID: store
+    circuitBreaker:
+      enabled: true
+      failMode: open
+      openTimeout: -1s
+persistence:
+  defaultStore: default
+  visibilityStore: default
+  numHistoryShards: 4
+  datastores:
+    default:
+      cassandra:
+        hosts: "127.0.0.1"
+        keyspace: "temporal"
+`,
+		expectedErrs: []string{
+			"line 12: invalid value for auth.ofga.circuitBreaker.failMode: \"open\", must be deny or cached",
+			"line 13: invalid value for auth.ofga.circuitBreaker.openTimeout: must not be negative",
+		},
+	}, {
+		desc: "error: invalid rate limit settings",
+		config: `
+auth:
+  enabled: true
+  googleClientID: google_client_id
+  ofga:
+    apiScheme: http
+    apiHost: openfga
+    apiPort: 8080
+    storeID: store
+  rateLimit:
+    enabled: true
+    read:
+      rps: -1
+    write:
+      rps: 10
+      burst: -5
+persistence:
+  defaultStore: default
+  visibilityStore: default
+  numHistoryShards: 4
+  datastores:
+    default:
+      cassandra:
+        hosts: "127.0.0.1"
+        keyspace: "temporal"
+`,
+		expectedErrs: []string{
+			"line 13: invalid value for auth.rateLimit.read.rps: must not be negative",
+			"line 16: invalid value for auth.rateLimit.write.burst: must not be negative",
+		},
+	}, {
+		desc: "error: invalid identity mode",
+		config: `
+auth:
+  enabled: true
+  googleClientID: google_client_id
+  ofga:
+    apiScheme: http
+    apiHost: openfga
+    apiPort: 8080
+    storeID: store
+  identity:
+    mode: stamp
+persistence:
+  defaultStore: default
+  visibilityStore: default
+  numHistoryShards: 4
+  datastores:
+    default:
+      cassandra:
+        hosts: "127.0.0.1"
+        keyspace: "temporal"
+`,
+		expectedErrs: []string{
+			"line 11: invalid value for auth.identity.mode: \"stamp\", must be override or validate",
+		},
+	}, {
+		desc: "error: introspection certificate without key",
+		config: validConfig + `
+http:
+  introspection:
+    listenAddress: 0.0.0.0:7244
+    certFile: /etc/temporal/tls/tls.crt
+`,
+		expectedErrs: []string{
+			"line 31: http.introspection.certFile and http.introspection.keyFile must be set together",
+		},
+	}, {
+		desc:         "error: invalid yaml",
+		config:       "auth:\n  enabled: true\n    ofga: {}\n",
+		expectedErrs: []string{"yaml: line 3: mapping values are not allowed in this context"},
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		c.Run(test.desc, func(c *qt.C) {
+			errs := authorizer.ValidateConfigWithAuth([]byte(test.config))
+
+			msgs := make([]string, 0, len(errs))
+			unknownKeys := 0
+			for _, err := range errs {
+				msgs = append(msgs, err.Error())
+				if err.UnknownKey {
+					unknownKeys++
+				}
+			}
+
+			c.Assert(msgs, qt.DeepEquals, append([]string{}, test.expectedErrs...))
+			c.Assert(unknownKeys, qt.Equals, test.expectedUnknownKeys)
+		})
+	}
+}
+
+func TestValidateDevelopmentConfig(t *testing.T) {
+	c := qt.New(t)
+
+	data, err := os.ReadFile("../config/development.yaml")
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(authorizer.ValidateConfigWithAuth(data), qt.HasLen, 0)
+}
@@ -0,0 +1,65 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fileWatcher calls load with the contents of file whenever they change.
+type fileWatcher struct {
+	file   string
+	source []byte
+	load   func([]byte) error
+	logger *zap.Logger
+}
+
+// watchFile reads file every pollInterval until ctx is done, and calls load
+// with its contents whenever they differ from source. If load fails, the
+// error is logged and load is called again once the file changes.
+func watchFile(ctx context.Context, file string, source []byte, pollInterval time.Duration, load func([]byte) error, logger *zap.Logger) {
+	w := &fileWatcher{file: file, source: source, load: load, logger: logger}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		w.poll()
+	}
+}
+
+// poll reads the file once, and loads it if it changed since the last read.
+func (w *fileWatcher) poll() {
+	source, err := os.ReadFile(w.file)
+	if err != nil {
+		w.logError(fmt.Sprintf("error reading %s, keeping the previous version: %v", w.file, err))
+		return
+	}
+	if bytes.Equal(source, w.source) {
+		return
+	}
+	w.source = source
+
+	if err := w.load(source); err != nil {
+		w.logError(fmt.Sprintf("error loading %s, keeping the previous version: %v", w.file, err))
+		return
+	}
+	if w.logger != nil {
+		w.logger.Info(fmt.Sprintf("%s reloaded", w.file))
+	}
+}
+
+func (w *fileWatcher) logError(msg string) {
+	if w.logger != nil {
+		w.logger.Error(msg)
+	}
+}
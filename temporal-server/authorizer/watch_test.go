@@ -0,0 +1,55 @@
+package authorizer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestFileWatcher(t *testing.T) {
+	c := qt.New(t)
+
+	file := filepath.Join(c.TempDir(), "watched.yaml")
+	err := os.WriteFile(file, []byte("v1"), 0o644)
+	c.Assert(err, qt.IsNil)
+
+	var loaded []string
+	w := &fileWatcher{file: file, source: []byte("v1"), load: func(source []byte) error {
+		loaded = append(loaded, string(source))
+		if string(source) == "invalid" {
+			return errors.New("invalid")
+		}
+		return nil
+	}}
+
+	// Unchanged contents are not loaded again.
+	w.poll()
+	c.Assert(loaded, qt.HasLen, 0)
+
+	// Changed contents are loaded.
+	err = os.WriteFile(file, []byte("v2"), 0o644)
+	c.Assert(err, qt.IsNil)
+	w.poll()
+	c.Assert(loaded, qt.DeepEquals, []string{"v2"})
+
+	// Contents which fail to load are only tried once.
+	err = os.WriteFile(file, []byte("invalid"), 0o644)
+	c.Assert(err, qt.IsNil)
+	w.poll()
+	w.poll()
+	c.Assert(loaded, qt.DeepEquals, []string{"v2", "invalid"})
+
+	// A file which can't be read is skipped until it is back.
+	err = os.Remove(file)
+	c.Assert(err, qt.IsNil)
+	w.poll()
+	c.Assert(loaded, qt.DeepEquals, []string{"v2", "invalid"})
+
+	err = os.WriteFile(file, []byte("v3"), 0o644)
+	c.Assert(err, qt.IsNil)
+	w.poll()
+	c.Assert(loaded, qt.DeepEquals, []string{"v2", "invalid", "v3"})
+}
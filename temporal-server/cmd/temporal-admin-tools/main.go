@@ -0,0 +1,214 @@
+// temporal-admin-tools manages the datastores of a Temporal Server, reading
+// their connection details from the persistence section of the server's
+// config. It also bundles the upstream SQL and Cassandra schema tools.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	auth "github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	"github.com/canonical/charmed-temporal-image/temporal-server/setup"
+
+	"github.com/urfave/cli/v2"
+
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/headers"
+	"go.temporal.io/server/common/log"
+	_ "go.temporal.io/server/common/persistence/sql/sqlplugin/mysql"      // needed to load mysql plugin
+	_ "go.temporal.io/server/common/persistence/sql/sqlplugin/postgresql" // needed to load postgresql plugin
+	_ "go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"     // needed to load sqlite plugin
+	"go.temporal.io/server/tools/cassandra"
+	"go.temporal.io/server/tools/sql"
+)
+
+func main() {
+	app := buildCLI()
+	_ = app.Run(os.Args)
+}
+
+func buildCLI() *cli.App {
+	app := cli.NewApp()
+	app.Name = "temporal-admin-tools"
+	app.Usage = "Manage the datastores of Temporal server"
+	app.Version = headers.ServerVersion
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:    "root",
+			Aliases: []string{"r"},
+			Value:   ".",
+			Usage:   "root directory of execution environment",
+			EnvVars: []string{config.EnvKeyRoot},
+		},
+		&cli.StringFlag{
+			Name:    "config",
+			Aliases: []string{"c"},
+			Value:   "config",
+			Usage:   "config dir path relative to root",
+			EnvVars: []string{config.EnvKeyConfigDir},
+		},
+		&cli.StringFlag{
+			Name:    "env",
+			Aliases: []string{"e"},
+			Value:   "development",
+			Usage:   "runtime environment",
+			EnvVars: []string{config.EnvKeyEnvironment},
+		},
+		&cli.StringFlag{
+			Name:    "zone",
+			Aliases: []string{"az"},
+			Usage:   "availability zone",
+			EnvVars: []string{config.EnvKeyAvailabilityZone, config.EnvKeyAvailabilityZoneTypo},
+		},
+	}
+
+	waitTimeoutFlag := &cli.DurationFlag{
+		Name:  "wait-timeout",
+		Value: 30 * time.Second,
+		Usage: "how long to wait for each datastore to be reachable",
+	}
+
+	app.Commands = []*cli.Command{
+		{
+			Name:  "schema",
+			Usage: "Inspect and migrate the schema of the configured datastores",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "version",
+					Usage:     "Show the schema versions expected by this Temporal server version",
+					ArgsUsage: " ",
+					Action: func(c *cli.Context) error {
+						cfg, err := loadConfig(c)
+						if err != nil {
+							return err
+						}
+
+						statuses, err := setup.SchemaVersions(cfg.Config)
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("Unable to get schema versions: %v.", err), 1)
+						}
+
+						fmt.Printf("Temporal server %s\n", headers.ServerVersion)
+						for _, status := range statuses {
+							if status.SchemaName == "" {
+								fmt.Printf("%s: %s\n", status.Target, status.Skipped)
+								continue
+							}
+							fmt.Printf("%s: schema %s at version %s\n", status.Target, status.SchemaName, status.LatestVersion)
+						}
+						return nil
+					},
+				},
+				{
+					Name:        "status",
+					Usage:       "Show the schema versions of the configured datastores",
+					ArgsUsage:   " ",
+					Description: "Connects to the datastores of the config and compares the version of their schema to the one expected by this Temporal server version. Exits with status 2 if any update is pending.",
+					Flags:       []cli.Flag{waitTimeoutFlag},
+					Action: func(c *cli.Context) error {
+						cfg, err := loadConfig(c)
+						if err != nil {
+							return err
+						}
+
+						opts := setup.Options{
+							WaitTimeout: c.Duration("wait-timeout"),
+						}
+						statuses, err := setup.Status(c.Context, cfg.Config, opts, newLogger(cfg))
+						pending := false
+						for _, status := range statuses {
+							fmt.Println(status)
+							if status.Behind() {
+								pending = true
+							}
+						}
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("Unable to get schema status: %v.", err), 1)
+						}
+						if pending {
+							return cli.Exit("", 2)
+						}
+						return nil
+					},
+				},
+				{
+					Name:        "migrate",
+					Usage:       "Create or update the schema of the configured datastores",
+					ArgsUsage:   " ",
+					Description: "Creates the databases or keyspaces of the config if needed, then creates or updates their schema to the version expected by this Temporal server version. Namespaces are not registered, use \"temporal-server setup\" for that.",
+					Flags: []cli.Flag{
+						&cli.BoolFlag{
+							Name:  "dry-run",
+							Usage: "show what would be done without changing anything",
+						},
+						waitTimeoutFlag,
+						&cli.IntFlag{
+							Name:  "replication-factor",
+							Value: 1,
+							Usage: "replication factor of the Cassandra keyspaces which are created",
+						},
+					},
+					Action: func(c *cli.Context) error {
+						cfg, err := loadConfig(c)
+						if err != nil {
+							return err
+						}
+
+						opts := setup.Options{
+							WaitTimeout:       c.Duration("wait-timeout"),
+							ReplicationFactor: c.Int("replication-factor"),
+						}
+						dryRun := c.Bool("dry-run")
+						report, err := setup.Migrate(c.Context, cfg.Config, opts, dryRun, newLogger(cfg))
+						for _, step := range report.Steps {
+							fmt.Println(step)
+						}
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("Migration failed: %v.", err), 1)
+						}
+
+						if dryRun {
+							fmt.Printf("Dry run complete, %d change(s) would be made.\n", report.Changed())
+						} else {
+							fmt.Printf("Migration complete, %d change(s) made.\n", report.Changed())
+						}
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:            "sql",
+			Usage:           "Run the upstream temporal-sql-tool, which takes its connection details as flags",
+			SkipFlagParsing: true,
+			Action: func(c *cli.Context) error {
+				return sql.RunTool(append([]string{"temporal-admin-tools sql"}, c.Args().Slice()...))
+			},
+		},
+		{
+			Name:            "cassandra",
+			Usage:           "Run the upstream temporal-cassandra-tool, which takes its connection details as flags",
+			SkipFlagParsing: true,
+			Action: func(c *cli.Context) error {
+				return cassandra.RunTool(append([]string{"temporal-admin-tools cassandra"}, c.Args().Slice()...))
+			},
+		},
+	}
+	return app
+}
+
+// loadConfig loads the server config selected by the global flags.
+func loadConfig(c *cli.Context) (*auth.ConfigWithAuth, error) {
+	configDir := path.Join(c.String("root"), c.String("config"))
+	cfg, err := auth.LoadConfigWithAuth(c.String("env"), configDir, c.String("zone"))
+	if err != nil {
+		return nil, cli.Exit(fmt.Sprintf("Unable to load configuration: %v.", err), 1)
+	}
+	return cfg, nil
+}
+
+func newLogger(cfg *auth.ConfigWithAuth) log.Logger {
+	return log.NewZapLogger(log.BuildZapLogger(cfg.Log))
+}
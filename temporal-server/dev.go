@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	auth "github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+
+	"go.temporal.io/server/common/cluster"
+	"go.temporal.io/server/common/config"
+	sqliteplugin "go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"
+	"go.temporal.io/server/common/primitives"
+	sqliteschema "go.temporal.io/server/schema/sqlite"
+)
+
+const (
+	devClusterName      = "active"
+	devStoreName        = "sqlite-default"
+	devBroadcastAddress = "127.0.0.1"
+	devFrontendPort     = 7233
+)
+
+// devServices are the ports of the services run in development mode, as in
+// config/development.yaml.
+var devServices = map[primitives.ServiceName]config.RPC{
+	primitives.FrontendService: {GRPCPort: devFrontendPort, MembershipPort: 6933},
+	primitives.HistoryService:  {GRPCPort: 7234, MembershipPort: 6934},
+	primitives.MatchingService: {GRPCPort: 7235, MembershipPort: 6935},
+	primitives.WorkerService:   {GRPCPort: 7239, MembershipPort: 6939},
+}
+
+// loadDevConfig returns the config used in development mode, which runs every
+// service in a single process and stores its data in SQLite, in memory if
+// dbFile is empty.
+//
+// The log, auth, http and dynamicConfigClient sections are read from the
+// config directory if it exists, so that auth can be tested locally. Every
+// other section is replaced by the development defaults.
+func loadDevConfig(env string, configDir string, zone string, dbFile string) (*auth.ConfigWithAuth, error) {
+	cfg := &auth.ConfigWithAuth{}
+
+	if _, err := os.Stat(configDir); err == nil {
+		cfg, err = auth.LoadConfigWithAuth(env, configDir, zone)
+		if err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	devConfig := newDevConfig(dbFile)
+	if cfg.Config != nil {
+		devConfig.Log = cfg.Log
+		devConfig.DynamicConfigClient = cfg.DynamicConfigClient
+	} else {
+		devConfig.Log.Stdout = true
+		devConfig.Log.Level = "info"
+	}
+	cfg.Config = devConfig
+
+	return cfg, nil
+}
+
+// newDevConfig returns a Temporal Server config for development mode.
+func newDevConfig(dbFile string) *config.Config {
+	sqlConfig := &config.SQL{
+		PluginName:        sqliteplugin.PluginName,
+		DatabaseName:      dbFile,
+		ConnectAttributes: map[string]string{"mode": "rwc", "setup": "true"},
+	}
+	if dbFile == "" {
+		// The schema of in-memory databases is always set up.
+		sqlConfig.DatabaseName = "temporal"
+		sqlConfig.ConnectAttributes = map[string]string{"mode": "memory", "cache": "shared"}
+	}
+
+	services := make(map[string]config.Service, len(devServices))
+	for name, rpc := range devServices {
+		rpc.BindOnLocalHost = true
+		services[string(name)] = config.Service{RPC: rpc}
+	}
+
+	return &config.Config{
+		Global: config.Global{
+			Membership: config.Membership{
+				MaxJoinDuration:  30 * time.Second,
+				BroadcastAddress: devBroadcastAddress,
+			},
+		},
+		Persistence: config.Persistence{
+			DefaultStore:     devStoreName,
+			VisibilityStore:  devStoreName,
+			NumHistoryShards: 1,
+			DataStores: map[string]config.DataStore{
+				devStoreName: {SQL: sqlConfig},
+			},
+		},
+		ClusterMetadata: &cluster.Config{
+			EnableGlobalNamespace:    false,
+			FailoverVersionIncrement: 10,
+			MasterClusterName:        devClusterName,
+			CurrentClusterName:       devClusterName,
+			ClusterInformation: map[string]cluster.ClusterInformation{
+				devClusterName: {
+					Enabled:                true,
+					InitialFailoverVersion: 1,
+					RPCAddress:             fmt.Sprintf("%s:%d", devBroadcastAddress, devFrontendPort),
+				},
+			},
+		},
+		DCRedirectionPolicy: config.DCRedirectionPolicy{Policy: "noop"},
+		Services:            services,
+		Archival: config.Archival{
+			History:    config.HistoryArchival{State: "disabled"},
+			Visibility: config.VisibilityArchival{State: "disabled"},
+		},
+		NamespaceDefaults: config.NamespaceDefaults{
+			Archival: config.ArchivalNamespaceDefaults{
+				History:    config.HistoryArchivalNamespaceDefaults{State: "disabled"},
+				Visibility: config.VisibilityArchivalNamespaceDefaults{State: "disabled"},
+			},
+		},
+		PublicClient: config.PublicClient{
+			HostPort: fmt.Sprintf("%s:%d", devBroadcastAddress, devFrontendPort),
+		},
+	}
+}
+
+// createDevNamespaces registers the given namespaces in the development
+// database, which also sets up its schema. Existing namespaces are left as
+// they are.
+func createDevNamespaces(cfg *config.Config, namespaces []string) error {
+	nsConfigs := make([]*sqliteschema.NamespaceConfig, 0, len(namespaces))
+	for _, ns := range namespaces {
+		nsConfigs = append(nsConfigs, sqliteschema.NewNamespaceConfig(devClusterName, ns, false))
+	}
+
+	sqlConfig := cfg.Persistence.DataStores[devStoreName].SQL
+	return sqliteschema.CreateNamespaces(sqlConfig, nsConfigs...)
+}
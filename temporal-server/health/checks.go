@@ -0,0 +1,156 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	auth "github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	"github.com/canonical/charmed-temporal-image/temporal-server/setup"
+
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/primitives"
+	"go.temporal.io/server/environment"
+	"go.temporal.io/server/service/frontend"
+)
+
+// grpcHealthServices are the names under which each service reports its
+// status through the gRPC health service. Services which are not listed, e.g.
+// the worker, are only checked to accept connections on their membership
+// port. The history and matching names are those of the unexported
+// constants of their handlers, which differ from their gRPC service names.
+var grpcHealthServices = map[primitives.ServiceName]string{
+	primitives.FrontendService:         frontend.WorkflowServiceName,
+	primitives.InternalFrontendService: frontend.WorkflowServiceName,
+	primitives.HistoryService:          "temporal.api.workflowservice.v1.HistoryService",
+	primitives.MatchingService:         "temporal.api.workflowservice.v1.MatchingService",
+}
+
+// ServiceCheck returns a Check which passes if the given service, running in
+// this process with the given config, is serving. Services serving gRPC over
+// TLS are only checked to accept connections, as probes hold no client
+// certificate.
+func ServiceCheck(service string, cfg *config.Config) Check {
+	return Check{
+		Name: "service/" + service,
+		Run: func(ctx context.Context) error {
+			rpc := cfg.Services[service].RPC
+			host, err := localAddress(rpc)
+			if err != nil {
+				return err
+			}
+
+			grpcService, ok := grpcHealthServices[primitives.ServiceName(service)]
+			if !ok {
+				return dialTCP(ctx, net.JoinHostPort(host, strconv.Itoa(rpc.MembershipPort)))
+			}
+
+			addr := net.JoinHostPort(host, strconv.Itoa(rpc.GRPCPort))
+			if serviceTLS(service, cfg).IsServerEnabled() {
+				return dialTCP(ctx, addr)
+			}
+			return checkGRPCHealth(ctx, addr, grpcService)
+		},
+	}
+}
+
+// PersistenceCheck returns a Check which passes if the datastores of the given
+// config can be connected to. The connections are reused between runs.
+func PersistenceCheck(cfg *config.Config, logger log.Logger) Check {
+	pinger := setup.NewPinger(cfg, logger)
+	return Check{
+		Name: "persistence",
+		Run:  pinger.Ping,
+	}
+}
+
+// OFGACheck returns a Check which passes if the OpenFGA server with the given
+// config is serving.
+func OFGACheck(cfg auth.AuthorizationConfig) Check {
+	return Check{
+		Name: "openfga",
+		Run: func(ctx context.Context) error {
+			url := fmt.Sprintf("%s://%s/healthz", cfg.APIScheme, net.JoinHostPort(cfg.APIHost, cfg.APIPort))
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("unexpected status %s", resp.Status)
+			}
+			return nil
+		},
+	}
+}
+
+// serviceTLS returns the TLS config of the gRPC server of the given service.
+func serviceTLS(service string, cfg *config.Config) *config.GroupTLS {
+	if primitives.ServiceName(service) == primitives.FrontendService {
+		return &cfg.Global.TLS.Frontend
+	}
+	return &cfg.Global.TLS.Internode
+}
+
+// localAddress returns the address on which a service of this process with
+// the given config can be reached, matching the address it listens on.
+func localAddress(rpc config.RPC) (string, error) {
+	switch {
+	case rpc.BindOnLocalHost:
+		return environment.GetLocalhostIP(), nil
+	case rpc.BindOnIP != "":
+		ip := net.ParseIP(rpc.BindOnIP)
+		if ip == nil {
+			return "", fmt.Errorf("invalid bindOnIP %q", rpc.BindOnIP)
+		}
+		if ip.IsUnspecified() {
+			return environment.GetLocalhostIP(), nil
+		}
+		return ip.String(), nil
+	default:
+		ip, err := config.ListenIP()
+		if err != nil {
+			return "", err
+		}
+		return ip.String(), nil
+	}
+}
+
+func dialTCP(ctx context.Context, addr string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func checkGRPCHealth(ctx context.Context, addr string, service string) error {
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("%s is %s", service, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+// Package health implements the health, readiness and version endpoints served
+// by the optional HTTP server of the Temporal Server, which Kubernetes probes
+// can use instead of running tctl or checking TCP ports.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/server/common/headers"
+	"go.uber.org/zap"
+)
+
+// checkTimeout is how long a readiness check may take before it fails.
+const checkTimeout = 5 * time.Second
+
+// Check is a readiness check.
+type Check struct {
+	// Name identifies the check in the readiness response, e.g.
+	// "service/frontend".
+	Name string
+	// Run returns an error if the check fails.
+	Run func(ctx context.Context) error
+}
+
+// StatusResponse is returned by the health endpoint.
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// ReadyResponse is returned by the readiness endpoint.
+type ReadyResponse struct {
+	Ready bool `json:"ready"`
+	// Checks holds "ok" or the error of each check, by name.
+	Checks map[string]string `json:"checks"`
+}
+
+// BuildInfo describes the build of the running server.
+type BuildInfo struct {
+	ServerVersion string `json:"serverVersion"`
+	GoVersion     string `json:"goVersion"`
+	Platform      string `json:"platform"`
+	// Revision is the VCS revision the binary was built from, if known.
+	Revision string `json:"revision,omitempty"`
+}
+
+// NewBuildInfo returns the BuildInfo of the running binary.
+func NewBuildInfo() BuildInfo {
+	info := BuildInfo{
+		ServerVersion: headers.ServerVersion,
+		GoVersion:     runtime.Version(),
+		Platform:      runtime.GOARCH,
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Revision = setting.Value
+			}
+		}
+	}
+	return info
+}
+
+// NewHealthzHandler returns an http.Handler which reports that the process is
+// up.
+func NewHealthzHandler(logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, StatusResponse{Status: "ok"}, logger)
+	})
+}
+
+type readyzHandler struct {
+	checks []Check
+	logger *zap.Logger
+}
+
+// NewReadyzHandler returns an http.Handler which runs the given checks
+// concurrently and reports the server as ready if all of them pass. If not,
+// it responds with http.StatusServiceUnavailable.
+func NewReadyzHandler(checks []Check, logger *zap.Logger) http.Handler {
+	return &readyzHandler{
+		checks: checks,
+		logger: logger,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *readyzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	errs := make([]error, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	resp := ReadyResponse{
+		Ready:  true,
+		Checks: make(map[string]string, len(h.checks)),
+	}
+	for i, check := range h.checks {
+		if errs[i] != nil {
+			resp.Ready = false
+			resp.Checks[check.Name] = errs[i].Error()
+			h.logger.Warn("readiness check failed", zap.String("check", check.Name), zap.Error(errs[i]))
+			continue
+		}
+		resp.Checks[check.Name] = "ok"
+	}
+
+	status := http.StatusOK
+	if !resp.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, resp, h.logger)
+}
+
+// runCheck runs check, failing if ctx is done first.
+func runCheck(ctx context.Context, check Check) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check.Run(ctx)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// NewVersionHandler returns an http.Handler which returns the given
+// BuildInfo.
+func NewVersionHandler(info BuildInfo, logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, info, logger)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}, logger *zap.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil && logger != nil {
+		logger.Error("error writing response", zap.Error(err))
+	}
+}
+
+// Shutdown reports the server as not ready once it starts shutting down, so
+// that it is removed from load balancing before its services stop.
+type Shutdown struct {
+	started atomic.Bool
+}
+
+// Start marks the server as shutting down.
+func (s *Shutdown) Start() {
+	s.started.Store(true)
+}
+
+// Check returns a Check which fails once Start is called.
+func (s *Shutdown) Check() Check {
+	return Check{
+		Name: "shutdown",
+		Run: func(ctx context.Context) error {
+			if s.started.Load() {
+				return errors.New("shutting down")
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,180 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/headers"
+	"go.temporal.io/server/service/frontend"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	auth "github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	"github.com/canonical/charmed-temporal-image/temporal-server/health"
+)
+
+func TestHealthz(t *testing.T) {
+	c := qt.New(t)
+
+	rec := httptest.NewRecorder()
+	health.NewHealthzHandler(zap.NewNop()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+	c.Assert(rec.Body.String(), qt.JSONEquals, health.StatusResponse{Status: "ok"})
+
+	rec = httptest.NewRecorder()
+	health.NewHealthzHandler(zap.NewNop()).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	c.Assert(rec.Code, qt.Equals, http.StatusMethodNotAllowed)
+}
+
+func TestReadyz(t *testing.T) {
+	c := qt.New(t)
+
+	passing := health.Check{
+		Name: "passing",
+		Run:  func(ctx context.Context) error { return nil },
+	}
+	failing := health.Check{
+		Name: "failing",
+		Run:  func(ctx context.Context) error { return errors.New("connection refused") },
+	}
+	hanging := health.Check{
+		Name: "hanging",
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+
+	tests := []struct {
+		about        string
+		checks       []health.Check
+		expectStatus int
+		expect       health.ReadyResponse
+	}{{
+		about:        "all checks pass",
+		checks:       []health.Check{passing},
+		expectStatus: http.StatusOK,
+		expect: health.ReadyResponse{
+			Ready:  true,
+			Checks: map[string]string{"passing": "ok"},
+		},
+	}, {
+		about:        "a check fails",
+		checks:       []health.Check{passing, failing},
+		expectStatus: http.StatusServiceUnavailable,
+		expect: health.ReadyResponse{
+			Checks: map[string]string{"passing": "ok", "failing": "connection refused"},
+		},
+	}}
+
+	for _, test := range tests {
+		c.Run(test.about, func(c *qt.C) {
+			rec := httptest.NewRecorder()
+			health.NewReadyzHandler(test.checks, zap.NewNop()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			c.Assert(rec.Code, qt.Equals, test.expectStatus)
+			c.Assert(rec.Body.String(), qt.JSONEquals, test.expect)
+		})
+	}
+
+	c.Run("a check times out", func(c *qt.C) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil).WithContext(ctx)
+		health.NewReadyzHandler([]health.Check{passing, hanging}, zap.NewNop()).ServeHTTP(rec, req)
+		c.Assert(rec.Code, qt.Equals, http.StatusServiceUnavailable)
+
+		var resp health.ReadyResponse
+		c.Assert(json.Unmarshal(rec.Body.Bytes(), &resp), qt.IsNil)
+		c.Assert(resp.Checks["hanging"], qt.Equals, context.DeadlineExceeded.Error())
+	})
+}
+
+func TestVersion(t *testing.T) {
+	c := qt.New(t)
+
+	info := health.NewBuildInfo()
+	c.Assert(info.ServerVersion, qt.Equals, headers.ServerVersion)
+
+	rec := httptest.NewRecorder()
+	health.NewVersionHandler(info, zap.NewNop()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+	c.Assert(rec.Body.String(), qt.JSONEquals, info)
+}
+
+func TestServiceCheck(t *testing.T) {
+	c := qt.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, qt.IsNil)
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	healthServer := grpchealth.NewServer()
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener) //nolint:errcheck
+	defer server.Stop()
+
+	cfg := &config.Config{
+		Services: map[string]config.Service{
+			"frontend": {RPC: config.RPC{GRPCPort: port, BindOnIP: "127.0.0.1"}},
+			"worker":   {RPC: config.RPC{MembershipPort: port, BindOnIP: "0.0.0.0"}},
+			"matching": {RPC: config.RPC{GRPCPort: port, BindOnIP: "127.0.0.1"}},
+		},
+	}
+	ctx := context.Background()
+
+	check := health.ServiceCheck("frontend", cfg)
+	c.Assert(check.Name, qt.Equals, "service/frontend")
+
+	healthServer.SetServingStatus(frontend.WorkflowServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	c.Assert(check.Run(ctx), qt.ErrorMatches, frontend.WorkflowServiceName+" is NOT_SERVING")
+
+	healthServer.SetServingStatus(frontend.WorkflowServiceName, healthpb.HealthCheckResponse_SERVING)
+	c.Assert(check.Run(ctx), qt.IsNil)
+
+	// The worker only needs to accept connections.
+	c.Assert(health.ServiceCheck("worker", cfg).Run(ctx), qt.IsNil)
+
+	// The matching service hasn't reported any status.
+	c.Assert(health.ServiceCheck("matching", cfg).Run(ctx), qt.ErrorMatches, ".*unknown service.*")
+}
+
+func TestOFGACheck(t *testing.T) {
+	c := qt.New(t)
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	c.Assert(err, qt.IsNil)
+	check := health.OFGACheck(auth.AuthorizationConfig{
+		APIScheme: u.Scheme,
+		APIHost:   u.Hostname(),
+		APIPort:   u.Port(),
+	})
+
+	c.Assert(check.Run(context.Background()), qt.IsNil)
+
+	status = http.StatusServiceUnavailable
+	c.Assert(check.Run(context.Background()), qt.ErrorMatches, "unexpected status 503 Service Unavailable")
+}
@@ -26,16 +26,22 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	stdlog "log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path"
-	"runtime"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 	_ "time/tzdata" // embed tzdata as a fallback
 
 	auth "github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+	"github.com/canonical/charmed-temporal-image/temporal-server/health"
+	"github.com/canonical/charmed-temporal-image/temporal-server/setup"
 
 	"github.com/urfave/cli/v2"
 
@@ -45,9 +51,13 @@ import (
 	"go.temporal.io/server/common/headers"
 	"go.temporal.io/server/common/log"
 	"go.temporal.io/server/common/log/tag"
+	"go.temporal.io/server/common/metrics"
 	_ "go.temporal.io/server/common/persistence/sql/sqlplugin/mysql"      // needed to load mysql plugin
 	_ "go.temporal.io/server/common/persistence/sql/sqlplugin/postgresql" // needed to load postgresql plugin
+	_ "go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"     // needed to load sqlite plugin
+	"go.temporal.io/server/common/primitives"
 	"go.temporal.io/server/temporal"
+	"google.golang.org/grpc"
 )
 
 // main entry point for the temporal server
@@ -111,11 +121,43 @@ func buildCLI() *cli.App {
 					Value:   cli.NewStringSlice(temporal.Services...),
 					Usage:   "service(s) to start",
 				},
+				&cli.BoolFlag{
+					Name:  "dev",
+					Usage: "run every service in a single process, storing data in SQLite. no database needs to be set up",
+				},
+				&cli.StringFlag{
+					Name:  "db-filename",
+					Usage: "file in which SQLite data is stored in development mode. if not set, data is kept in memory and lost on exit",
+				},
+				&cli.StringSliceFlag{
+					Name:    "namespace",
+					Aliases: []string{"n"},
+					Value:   cli.NewStringSlice("default"),
+					Usage:   "namespace(s) to create in development mode",
+				},
+				&cli.StringFlag{
+					Name:  "schema-check",
+					Value: schemaCheckFail,
+					Usage: "what to do when the schema version of the datastores differs from the one expected by this server: fail, warn or off",
+				},
+				&cli.DurationFlag{
+					Name:    "drain-period",
+					Usage:   "how long to report the server as not ready before stopping its services on SIGTERM, so that load balancers stop sending it requests",
+					EnvVars: []string{"TEMPORAL_DRAIN_PERIOD"},
+				},
 			},
 			Before: func(c *cli.Context) error {
 				if c.Args().Len() > 0 {
 					return cli.Exit("ERROR: start command doesn't support arguments. Use --service flag instead.", 1)
 				}
+				if !c.Bool("dev") && (c.IsSet("db-filename") || c.IsSet("namespace")) {
+					return cli.Exit("ERROR: --db-filename and --namespace can only be used with --dev.", 1)
+				}
+				switch c.String("schema-check") {
+				case schemaCheckFail, schemaCheckWarn, schemaCheckOff:
+				default:
+					return cli.Exit(fmt.Sprintf("ERROR: --schema-check must be one of %s, %s or %s.", schemaCheckFail, schemaCheckWarn, schemaCheckOff), 1)
+				}
 				return nil
 			},
 			Action: func(c *cli.Context) error {
@@ -130,20 +172,44 @@ func buildCLI() *cli.App {
 					services = strings.Split(c.String("services"), ",")
 				}
 
-				cfg, err := auth.LoadConfigWithAuth(env, configDir, zone)
+				var cfg *auth.ConfigWithAuth
+				var err error
+				if c.Bool("dev") {
+					if !c.IsSet("service") && !c.IsSet("services") {
+						services = temporal.DefaultServices
+					}
+					cfg, err = loadDevConfig(env, configDir, zone, c.String("db-filename"))
+				} else {
+					cfg, err = auth.LoadConfigWithAuth(env, configDir, zone)
+				}
 				if err != nil {
 					return cli.Exit(fmt.Sprintf("Unable to load configuration: %v.", err), 1)
 				}
 
 				zapLogger := log.BuildZapLogger(cfg.Log)
 				logger := log.NewZapLogger(zapLogger)
+				buildInfo := health.NewBuildInfo()
 				logger.Info("Build info",
 					tag.Timestamp(time.Now()),
-					tag.NewStringTag("platform", runtime.GOARCH),
-					tag.NewStringTag("go-version", runtime.Version()),
-					tag.NewStringTag("server-version", headers.ServerVersion),
+					tag.NewStringTag("platform", buildInfo.Platform),
+					tag.NewStringTag("go-version", buildInfo.GoVersion),
+					tag.NewStringTag("server-version", buildInfo.ServerVersion),
 				)
 
+				if c.Bool("dev") {
+					if err := createDevNamespaces(cfg.Config, c.StringSlice("namespace")); err != nil {
+						return cli.Exit(fmt.Sprintf("Unable to set up development database: %v.", err), 1)
+					}
+					logger.Info("Running in development mode",
+						tag.NewStringTag("database", cfg.Persistence.DataStores[devStoreName].SQL.DatabaseName),
+						tag.NewStringTag("namespaces", strings.Join(c.StringSlice("namespace"), ",")),
+					)
+				}
+
+				if err := checkSchema(c.Context, cfg.Config, c.String("schema-check"), migrateCommand(c), logger); err != nil {
+					return cli.Exit(fmt.Sprintf("Schema check failed: %v.", err), 1)
+				}
+
 				var dynamicConfigClient dynamicconfig.Client
 				if cfg.DynamicConfigClient != nil {
 					dynamicConfigClient, err = dynamicconfig.NewFileBasedClient(cfg.DynamicConfigClient, logger, temporal.InterruptCh())
@@ -157,37 +223,265 @@ func buildCLI() *cli.App {
 
 				claimMapper := authorization.NewNoopClaimMapper()
 				authorizer := authorization.NewNoopAuthorizer()
+				var tokenClaimMapper *auth.TokenClaimMapper
 				if cfg.Auth.Enabled {
 					ctx := context.Background()
-					claimMapper, err = auth.NewTokenClaimMapper(ctx, cfg, zapLogger)
+					tokenClaimMapper, err = auth.NewTokenClaimMapper(ctx, cfg, zapLogger)
 					if err != nil {
 						return cli.Exit(fmt.Sprintf("Unable to initialize claim mapper: %v.", err), 1)
 					}
+					claimMapper = tokenClaimMapper
 					authorizer = auth.NewAuthorizer(zapLogger)
+					if cfg.Auth.Policy.File != "" {
+						authorizer, err = auth.NewPolicyAuthorizer(ctx, cfg.Auth.Policy, zapLogger)
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("Unable to initialize policy authorizer: %v.", err), 1)
+						}
+					}
+				}
+
+				shutdown := &health.Shutdown{}
+				if c.Duration("drain-period") > 0 && cfg.HTTP.ListenAddress == "" {
+					logger.Warn("--drain-period is set but http.listenAddress is not, so the server is never reported as not ready and load balancers keep sending it requests until it stops",
+						tag.NewDurationTag("drain-period", c.Duration("drain-period")))
+				}
+				if cfg.HTTP.ListenAddress != "" {
+					checks := []health.Check{shutdown.Check(), health.PersistenceCheck(cfg.Config, logger)}
+					for _, service := range services {
+						checks = append(checks, health.ServiceCheck(service, cfg.Config))
+					}
+					if cfg.Auth.Enabled {
+						checks = append(checks, health.OFGACheck(cfg.Auth.OFGA))
+					}
+
+					mux := http.NewServeMux()
+					mux.Handle("/healthz", health.NewHealthzHandler(zapLogger))
+					mux.Handle("/readyz", health.NewReadyzHandler(checks, zapLogger))
+					mux.Handle("/version", health.NewVersionHandler(buildInfo, zapLogger))
+
+					httpServer := startHTTPServer(cfg.HTTP.ListenAddress, mux, "", "", logger)
+					defer httpServer.Shutdown(context.Background())
+				}
+
+				// The introspection endpoints receive access tokens, so they
+				// are not served by the probe server above.
+				if introspection := cfg.HTTP.Introspection; introspection.ListenAddress != "" {
+					mux := http.NewServeMux()
+					mux.Handle("/whoami", auth.NewWhoAmIHandler(claimMapper, zapLogger))
+					if tokenClaimMapper != nil && slices.Contains(services, string(primitives.FrontendService)) {
+						mux.Handle("/debug/claims", auth.NewClaimsIntrospectionHandler(tokenClaimMapper, authorizer, zapLogger))
+					}
+
+					if introspection.CertFile == "" {
+						logger.Warn("Introspection endpoints are served over plain HTTP, their address must not be exposed outside the pod",
+							tag.NewStringTag("address", introspection.ListenAddress))
+					}
+					introspectionServer := startHTTPServer(introspection.ListenAddress, mux, introspection.CertFile, introspection.KeyFile, logger)
+					defer introspectionServer.Shutdown(context.Background())
+				}
+
+				metricsHandler, err := metrics.MetricsHandlerFromConfig(logger, cfg.Global.Metrics)
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Unable to create metrics handler. Error: %v", err), 1)
+				}
+				sharedMetrics := sharedMetricsHandler{metricsHandler}
+				defer sharedMetrics.stop(logger)
+
+				var frontendInterceptors []grpc.UnaryServerInterceptor
+				if cfg.Auth.Enabled && cfg.Auth.RateLimit.Enabled {
+					rateLimiter := auth.NewRateLimiter(cfg.Auth.RateLimit, sharedMetrics, zapLogger)
+					frontendInterceptors = append(frontendInterceptors, rateLimiter.Intercept)
+				}
+				if cfg.Auth.Enabled && cfg.Auth.Identity.Mode != "" {
+					identityStamper := auth.NewIdentityStamper(cfg.Auth.Identity, zapLogger)
+					frontendInterceptors = append(frontendInterceptors, identityStamper.Intercept)
 				}
 
-				server, err := temporal.NewServer(
-					temporal.ForServices(services),
+				servers, err := newServers(services, sharedMetrics,
 					temporal.WithConfig(cfg.Config),
-					temporal.InterruptOn(temporal.InterruptCh()),
 					temporal.WithDynamicConfigClient(dynamicConfigClient),
 					temporal.WithLogger(logger),
 					temporal.WithAuthorizer(authorizer),
 					temporal.WithClaimMapper(func(cfg *config.Config) authorization.ClaimMapper {
 						return claimMapper
 					}),
+					temporal.WithChainedFrontendGrpcInterceptors(frontendInterceptors...),
 				)
 				if err != nil {
 					return cli.Exit(fmt.Sprintf("Unable to create server. Error: %v", err), 1)
 				}
 
-				err = server.Start()
-				if err != nil {
+				interruptCh := make(chan os.Signal, 2)
+				signal.Notify(interruptCh, os.Interrupt, syscall.SIGTERM)
+
+				logger.Info("Starting server for services", tag.Value(services))
+				if err := startServers(servers); err != nil {
+					stopServers(servers, logger)
 					return cli.Exit(fmt.Sprintf("Unable to start server. Error: %v", err), 1)
 				}
+
+				waitForShutdown(interruptCh, c.Duration("drain-period"), shutdown.Start, servers, logger)
 				return cli.Exit("All services are stopped.", 0)
 			},
 		},
+		{
+			Name:      "setup",
+			Usage:     "Set up the datastores and namespaces of Temporal server",
+			ArgsUsage: " ",
+			Description: "Waits for the datastores of the config to be reachable, creates their databases or keyspaces " +
+				"if needed, creates or updates their schema to the latest version and registers namespaces. " +
+				"Every step is skipped if already done, so it is safe to run before every start.",
+			Flags: []cli.Flag{
+				&cli.DurationFlag{
+					Name:  "wait-timeout",
+					Value: 2 * time.Minute,
+					Usage: "how long to wait for each datastore to be reachable",
+				},
+				&cli.StringSliceFlag{
+					Name:    "namespace",
+					Aliases: []string{"n"},
+					Value:   cli.NewStringSlice("default"),
+					Usage:   "namespace(s) to register",
+				},
+				&cli.BoolFlag{
+					Name:  "skip-namespaces",
+					Usage: "do not register any namespace",
+				},
+				&cli.DurationFlag{
+					Name:  "namespace-retention",
+					Value: 24 * time.Hour,
+					Usage: "workflow execution retention period of the namespaces which are registered",
+				},
+				&cli.IntFlag{
+					Name:  "replication-factor",
+					Value: 1,
+					Usage: "replication factor of the Cassandra keyspaces which are created",
+				},
+			},
+			Before: func(c *cli.Context) error {
+				if c.Args().Len() > 0 {
+					return cli.Exit("ERROR: setup command doesn't support arguments.", 1)
+				}
+				return nil
+			},
+			Action: func(c *cli.Context) error {
+				env := c.String("env")
+				zone := c.String("zone")
+				configDir := path.Join(c.String("root"), c.String("config"))
+
+				cfg, err := auth.LoadConfigWithAuth(env, configDir, zone)
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Unable to load configuration: %v.", err), 1)
+				}
+
+				logger := log.NewZapLogger(log.BuildZapLogger(cfg.Log))
+
+				opts := setup.Options{
+					WaitTimeout:        c.Duration("wait-timeout"),
+					Namespaces:         c.StringSlice("namespace"),
+					NamespaceRetention: c.Duration("namespace-retention"),
+					ReplicationFactor:  c.Int("replication-factor"),
+				}
+				if c.Bool("skip-namespaces") {
+					opts.Namespaces = nil
+				}
+
+				report, err := setup.Run(c.Context, cfg.Config, opts, logger)
+				for _, step := range report.Steps {
+					fmt.Println(step)
+				}
+				if err != nil {
+					return cli.Exit(fmt.Sprintf("Setup failed: %v.", err), 1)
+				}
+
+				fmt.Printf("Setup complete, %d change(s) made.\n", report.Changed())
+				return nil
+			},
+		},
+		{
+			Name:  "policy",
+			Usage: "Work with authorization policies",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "test",
+					Usage:     "Evaluate a policy against fixtures",
+					ArgsUsage: "FIXTURES_FILE...",
+					Description: "Compiles the CEL policy given by --policy and evaluates it against every fixture of the " +
+						"given YAML files, without connecting to any service. Exits with an error if any fixture fails.",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "policy",
+							Usage:    "path of the policy file",
+							Required: true,
+						},
+					},
+					Before: func(c *cli.Context) error {
+						if c.Args().Len() == 0 {
+							return cli.Exit("ERROR: policy test command requires at least one fixtures file.", 1)
+						}
+						return nil
+					},
+					Action: func(c *cli.Context) error {
+						source, err := os.ReadFile(c.String("policy"))
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("Unable to read policy: %v.", err), 1)
+						}
+						policy, err := auth.CompilePolicy(string(source))
+						if err != nil {
+							return cli.Exit(fmt.Sprintf("Unable to compile policy: %v.", err), 1)
+						}
+
+						var failed, total int
+						for _, file := range c.Args().Slice() {
+							fixtures, err := loadPolicyFixtures(file)
+							if err != nil {
+								return cli.Exit(fmt.Sprintf("Unable to load fixtures: %v.", err), 1)
+							}
+							for _, fixture := range fixtures {
+								total++
+								if err := fixture.run(policy); err != nil {
+									failed++
+									fmt.Printf("FAIL %s: %v\n", fixture.Name, err)
+									continue
+								}
+								fmt.Printf("PASS %s\n", fixture.Name)
+							}
+						}
+
+						if failed > 0 {
+							return cli.Exit(fmt.Sprintf("%d of %d fixture(s) failed.", failed, total), 1)
+						}
+						fmt.Printf("All %d fixture(s) passed.\n", total)
+						return nil
+					},
+				},
+			},
+		},
 	}
 	return app
 }
+
+// startHTTPServer starts serving the given handler on the given address in the
+// background, over TLS if certFile and keyFile are set.
+func startHTTPServer(addr string, handler http.Handler, certFile string, keyFile string, logger log.Logger) *http.Server {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		logger.Info("Starting HTTP server", tag.NewStringTag("address", addr))
+		var err error
+		if certFile != "" {
+			err = httpServer.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("HTTP server error", tag.Error(err))
+		}
+	}()
+
+	return httpServer
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"go.temporal.io/server/common/authorization"
+	"gopkg.in/yaml.v3"
+
+	auth "github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+)
+
+// policyFixture is a request along with the decision a policy is expected to
+// make for it.
+type policyFixture struct {
+	Name   string `yaml:"name"`
+	Claims struct {
+		Subject    string            `yaml:"subject"`
+		System     string            `yaml:"system"`
+		Namespaces map[string]string `yaml:"namespaces"`
+	} `yaml:"claims"`
+	API       string            `yaml:"api"`
+	Namespace string            `yaml:"namespace"`
+	Headers   map[string]string `yaml:"headers"`
+	// Expect is either "allow" or "deny".
+	Expect string `yaml:"expect"`
+}
+
+// loadPolicyFixtures loads the list of fixtures in the given YAML file.
+func loadPolicyFixtures(file string) ([]policyFixture, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []policyFixture
+	if err := yaml.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	for i, fixture := range fixtures {
+		if fixture.Name == "" {
+			fixtures[i].Name = fmt.Sprintf("%s[%d]", file, i)
+		}
+		if fixture.Expect != "allow" && fixture.Expect != "deny" {
+			return nil, fmt.Errorf("%s: fixture %s: invalid expect %q, must be allow or deny", file, fixtures[i].Name, fixture.Expect)
+		}
+	}
+	return fixtures, nil
+}
+
+// run evaluates the given policy against the fixture, and returns an error if
+// the decision is not the expected one.
+func (f policyFixture) run(policy *auth.Policy) error {
+	claims := &authorization.Claims{
+		Subject:    f.Claims.Subject,
+		Namespaces: make(map[string]authorization.Role, len(f.Claims.Namespaces)),
+	}
+	var err error
+	if claims.System, err = auth.ParseRoleName(f.Claims.System); err != nil {
+		return err
+	}
+	for ns, name := range f.Claims.Namespaces {
+		if claims.Namespaces[ns], err = auth.ParseRoleName(name); err != nil {
+			return err
+		}
+	}
+
+	allowed, err := policy.Evaluate(auth.PolicyInput{
+		Claims:    claims,
+		APIName:   f.API,
+		Namespace: f.Namespace,
+		Headers:   f.Headers,
+	})
+	if err != nil {
+		return err
+	}
+
+	decision := "deny"
+	if allowed {
+		decision = "allow"
+	}
+	if decision != f.Expect {
+		return errors.New("expected " + f.Expect + ", got " + decision)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	auth "github.com/canonical/charmed-temporal-image/temporal-server/authorizer"
+)
+
+func TestPolicyFixtures(t *testing.T) {
+	c := qt.New(t)
+
+	source, err := os.ReadFile("testdata/policy.cel")
+	c.Assert(err, qt.IsNil)
+	policy, err := auth.CompilePolicy(string(source))
+	c.Assert(err, qt.IsNil)
+
+	fixtures, err := loadPolicyFixtures("testdata/policy-fixtures.yaml")
+	c.Assert(err, qt.IsNil)
+	c.Assert(fixtures, qt.HasLen, 4)
+
+	var errs []string
+	for _, fixture := range fixtures {
+		if err := fixture.run(policy); err != nil {
+			errs = append(errs, fixture.Name+": "+err.Error())
+		}
+	}
+	c.Assert(errs, qt.DeepEquals, []string{"expected to fail: expected allow, got deny"})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/setup"
+
+	"github.com/urfave/cli/v2"
+
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/headers"
+	"go.temporal.io/server/common/log"
+)
+
+// The modes of the schema check run before starting the server.
+const (
+	schemaCheckFail = "fail"
+	schemaCheckWarn = "warn"
+	schemaCheckOff  = "off"
+)
+
+// schemaCheckTimeout is how long the schema check waits for each datastore to
+// be reachable.
+const schemaCheckTimeout = 30 * time.Second
+
+// checkSchema compares the schema version of the default and visibility
+// stores to the one this server build expects. In schemaCheckFail mode, a
+// schema which is behind or ahead is an error. In schemaCheckWarn mode, it is
+// logged instead. migrateCmd is the command which upgrades the schema.
+func checkSchema(ctx context.Context, cfg *config.Config, mode string, migrateCmd string, logger log.Logger) error {
+	if mode == schemaCheckOff {
+		return nil
+	}
+
+	statuses, err := setup.Status(ctx, cfg, setup.Options{WaitTimeout: schemaCheckTimeout}, logger)
+	problems := schemaProblems(statuses, migrateCmd)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("unable to check the schema version: %v", err))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	if mode == schemaCheckWarn {
+		for _, problem := range problems {
+			logger.Warn("Schema check failed: " + problem)
+		}
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// schemaProblems describes the datastores whose schema is behind or ahead of
+// the one this server build expects.
+func schemaProblems(statuses []setup.SchemaStatus, migrateCmd string) []string {
+	var problems []string
+	for _, s := range statuses {
+		switch {
+		case s.Behind() && s.CurrentVersion == "":
+			problems = append(problems, fmt.Sprintf(
+				"%s has no schema, Temporal server %s expects schema %s at version %s, run %q to create it",
+				s.Target, headers.ServerVersion, s.SchemaName, s.LatestVersion, migrateCmd))
+		case s.Behind():
+			problems = append(problems, fmt.Sprintf(
+				"%s is at schema %s version %s, behind version %s expected by Temporal server %s, run %q to upgrade it",
+				s.Target, s.SchemaName, s.CurrentVersion, s.LatestVersion, headers.ServerVersion, migrateCmd))
+		case s.Ahead():
+			problems = append(problems, fmt.Sprintf(
+				"%s is at schema %s version %s, ahead of version %s expected by Temporal server %s, upgrade the server or start it with --schema-check=%s",
+				s.Target, s.SchemaName, s.CurrentVersion, s.LatestVersion, headers.ServerVersion, schemaCheckWarn))
+		}
+	}
+	return problems
+}
+
+// migrateCommand returns the temporal-admin-tools command which migrates the
+// schema of the datastores of the config selected by the global flags of c.
+func migrateCommand(c *cli.Context) string {
+	args := []string{"temporal-admin-tools"}
+	for _, name := range []string{"root", "config", "env", "zone"} {
+		if c.IsSet(name) || name == "env" {
+			args = append(args, "--"+name, c.String(name))
+		}
+	}
+	return strings.Join(append(args, "schema", "migrate"), " ")
+}
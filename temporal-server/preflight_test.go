@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/headers"
+
+	"github.com/canonical/charmed-temporal-image/temporal-server/setup"
+)
+
+func TestSchemaProblems(t *testing.T) {
+	c := qt.New(t)
+
+	migrateCmd := "temporal-admin-tools --env docker schema migrate"
+	statuses := []setup.SchemaStatus{{
+		Target:        `default store "pg"`,
+		SchemaName:    "postgresql/v12/temporal",
+		LatestVersion: "1.11",
+		Missing:       true,
+	}, {
+		Target:         `visibility store "pg-visibility"`,
+		SchemaName:     "postgresql/v12/visibility",
+		CurrentVersion: "1.2",
+		LatestVersion:  "1.4",
+		Pending:        []setup.SchemaUpdate{{Version: "1.3"}, {Version: "1.4"}},
+	}, {
+		Target:         `visibility store "pg-secondary"`,
+		SchemaName:     "postgresql/v12/visibility",
+		CurrentVersion: "1.5",
+		LatestVersion:  "1.4",
+	}, {
+		Target:         `visibility store "pg-up-to-date"`,
+		SchemaName:     "postgresql/v12/visibility",
+		CurrentVersion: "1.4",
+		LatestVersion:  "1.4",
+	}, {
+		Target:  `visibility store "es"`,
+		Skipped: "skipped",
+	}}
+
+	c.Assert(schemaProblems(statuses, migrateCmd), qt.DeepEquals, []string{
+		`default store "pg" has no schema, Temporal server ` + headers.ServerVersion + ` expects schema postgresql/v12/temporal at version 1.11, run "temporal-admin-tools --env docker schema migrate" to create it`,
+		`visibility store "pg-visibility" is at schema postgresql/v12/visibility version 1.2, behind version 1.4 expected by Temporal server ` + headers.ServerVersion + `, run "temporal-admin-tools --env docker schema migrate" to upgrade it`,
+		`visibility store "pg-secondary" is at schema postgresql/v12/visibility version 1.5, ahead of version 1.4 expected by Temporal server ` + headers.ServerVersion + `, upgrade the server or start it with --schema-check=warn`,
+	})
+}
@@ -0,0 +1,239 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	commongocql "go.temporal.io/server/common/persistence/nosql/nosqlplugin/cassandra/gocql"
+	"go.temporal.io/server/common/resolver"
+)
+
+const (
+	cassandraSystemKeyspace = "system"
+	cassandraTimeout        = 30 * time.Second
+)
+
+// The statements below are those of temporal-cassandra-tool, whose client is
+// not exported.
+const (
+	readSchemaVersionCQL        = `SELECT curr_version from schema_version where keyspace_name=?`
+	writeSchemaVersionCQL       = `INSERT into schema_version(keyspace_name, creation_time, curr_version, min_compatible_version) VALUES (?,?,?,?)`
+	writeSchemaUpdateHistoryCQL = `INSERT into schema_update_history(year, month, update_time, old_version, new_version, manifest_md5, description) VALUES(?,?,?,?,?,?,?)`
+	listKeyspacesCQL            = `SELECT keyspace_name FROM system_schema.keyspaces WHERE keyspace_name=?`
+	listTablesCQL               = `SELECT table_name FROM system_schema.tables WHERE keyspace_name=? AND table_name=?`
+
+	createSchemaVersionTableCQL = `CREATE TABLE IF NOT EXISTS schema_version(keyspace_name text PRIMARY KEY, ` +
+		`creation_time timestamp, ` +
+		`curr_version text, ` +
+		`min_compatible_version text);`
+
+	createSchemaUpdateHistoryTableCQL = `CREATE TABLE IF NOT EXISTS schema_update_history(` +
+		`year int, ` +
+		`month int, ` +
+		`update_time timestamp, ` +
+		`description text, ` +
+		`manifest_md5 text, ` +
+		`new_version text, ` +
+		`old_version text, ` +
+		`PRIMARY KEY ((year, month), update_time));`
+
+	createKeyspaceCQL = `CREATE KEYSPACE IF NOT EXISTS %v ` +
+		`WITH replication = { 'class' : 'SimpleStrategy', 'replication_factor' : %v};`
+
+	createKeyspaceNetworkTopologyCQL = `CREATE KEYSPACE IF NOT EXISTS %v ` +
+		`WITH replication = { 'class' : 'NetworkTopologyStrategy', '%v' : %v};`
+)
+
+func setupCassandraStore(ctx context.Context, s store, opts Options, report *Report, logger log.Logger) error {
+	cfg := *s.cfg.Cassandra
+
+	schemaName, err := s.schemaName()
+	if err != nil {
+		return err
+	}
+
+	db, err := openCassandraStore(ctx, s, opts, logger)
+	if errors.Is(err, errStoreMissing) {
+		system, err := newCassandraDB(systemKeyspace(cfg), logger)
+		if err != nil {
+			return err
+		}
+		defer system.Close()
+
+		if err := system.createKeyspace(cfg.Keyspace, cfg.Datacenter, opts.ReplicationFactor); err != nil {
+			return fmt.Errorf("unable to create keyspace %q: %w", cfg.Keyspace, err)
+		}
+		report.add(s.target(), true, "created keyspace %q with replication factor %d", cfg.Keyspace, opts.ReplicationFactor)
+
+		db, err = newCassandraDB(cfg, logger)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return updateSchema(db, schemaName, s.target(), report, logger)
+}
+
+// openCassandraStore waits for the cluster of s to be reachable, then
+// connects to its keyspace. If the keyspace doesn't exist yet, the returned
+// error wraps errStoreMissing.
+func openCassandraStore(ctx context.Context, s store, opts Options, logger log.Logger) (*cassandraDB, error) {
+	cfg := *s.cfg.Cassandra
+
+	// The keyspace may not exist yet, so wait for the cluster by connecting
+	// to the system keyspace.
+	var system *cassandraDB
+	err := waitFor(ctx, opts.WaitTimeout, fmt.Sprintf("cassandra cluster %s", cfg.Hosts), logger, func() error {
+		var err error
+		system, err = newCassandraDB(systemKeyspace(cfg), logger)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer system.Close()
+
+	exists, err := system.keyspaceExists(cfg.Keyspace)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: keyspace %q does not exist", errStoreMissing, cfg.Keyspace)
+	}
+
+	return newCassandraDB(cfg, logger)
+}
+
+// systemKeyspace returns cfg with the keyspace set to the system keyspace,
+// which always exists.
+func systemKeyspace(cfg config.Cassandra) config.Cassandra {
+	cfg.Keyspace = cassandraSystemKeyspace
+	return cfg
+}
+
+// cassandraDB implements schemaDB for a Cassandra keyspace.
+type cassandraDB struct {
+	keyspace string
+	session  commongocql.Session
+}
+
+var _ schemaDB = (*cassandraDB)(nil)
+
+func newCassandraDB(cfg config.Cassandra, logger log.Logger) (*cassandraDB, error) {
+	cfg.ConnectTimeout = cassandraTimeout
+	session, err := commongocql.NewSession(
+		func() (*gocql.ClusterConfig, error) {
+			return commongocql.NewCassandraCluster(cfg, resolver.NewNoopResolver())
+		},
+		logger,
+		metrics.NoopMetricsHandler,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cassandraDB{
+		keyspace: cfg.Keyspace,
+		session:  session,
+	}, nil
+}
+
+func (db *cassandraDB) keyspaceExists(name string) (bool, error) {
+	iter := db.session.Query(listKeyspacesCQL, name).Iter()
+	var found string
+	exists := iter.Scan(&found)
+	if err := iter.Close(); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (db *cassandraDB) createKeyspace(name string, datacenter string, replicas int) error {
+	if datacenter != "" {
+		return db.Exec(fmt.Sprintf(createKeyspaceNetworkTopologyCQL, name, datacenter, replicas))
+	}
+	return db.Exec(fmt.Sprintf(createKeyspaceCQL, name, replicas))
+}
+
+// Exec implements schema.DB.
+func (db *cassandraDB) Exec(stmt string, args ...interface{}) error {
+	if err := db.session.Query(stmt, args...).Exec(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cassandraTimeout)
+	defer cancel()
+	return db.session.AwaitSchemaAgreement(ctx)
+}
+
+// DropAllTables implements schema.DB. Setup never overwrites existing data.
+func (db *cassandraDB) DropAllTables() error {
+	return fmt.Errorf("dropping tables is not supported")
+}
+
+// CreateSchemaVersionTables implements schema.DB.
+func (db *cassandraDB) CreateSchemaVersionTables() error {
+	if err := db.Exec(createSchemaVersionTableCQL); err != nil {
+		return err
+	}
+	return db.Exec(createSchemaUpdateHistoryTableCQL)
+}
+
+// ReadSchemaVersion implements schema.DB.
+func (db *cassandraDB) ReadSchemaVersion() (string, error) {
+	iter := db.session.Query(readSchemaVersionCQL, db.keyspace).Iter()
+	var version string
+	found := iter.Scan(&version)
+	err := iter.Close()
+	if err == nil && !found {
+		err = fmt.Errorf("%w for keyspace %q", errNoSchemaVersion, db.keyspace)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to get current schema version from Cassandra: %w", err)
+	}
+	return version, nil
+}
+
+// hasVersionTables implements schemaDB.
+func (db *cassandraDB) hasVersionTables() (bool, error) {
+	iter := db.session.Query(listTablesCQL, db.keyspace, "schema_version").Iter()
+	var found string
+	exists := iter.Scan(&found)
+	if err := iter.Close(); err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+// UpdateSchemaVersion implements schema.DB.
+func (db *cassandraDB) UpdateSchemaVersion(newVersion string, minCompatibleVersion string) error {
+	return db.session.Query(writeSchemaVersionCQL, db.keyspace, time.Now().UTC(), newVersion, minCompatibleVersion).Exec()
+}
+
+// WriteSchemaUpdateLog implements schema.DB.
+func (db *cassandraDB) WriteSchemaUpdateLog(oldVersion string, newVersion string, manifestMD5 string, desc string) error {
+	now := time.Now().UTC()
+	query := db.session.Query(writeSchemaUpdateHistoryCQL)
+	query.Bind(now.Year(), int(now.Month()), now, oldVersion, newVersion, manifestMD5, desc)
+	return query.Exec()
+}
+
+// Close implements schema.DB.
+func (db *cassandraDB) Close() {
+	db.session.Close()
+}
+
+// Type implements schema.DB.
+func (db *cassandraDB) Type() string {
+	return "cassandra"
+}
@@ -0,0 +1,80 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.temporal.io/api/serviceerror"
+
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/persistence"
+	persistenceclient "go.temporal.io/server/common/persistence/client"
+	"go.temporal.io/server/common/persistence/serialization"
+	"go.temporal.io/server/common/primitives/timestamp"
+	"go.temporal.io/server/common/resolver"
+	sqliteschema "go.temporal.io/server/schema/sqlite"
+)
+
+// registerNamespaces registers the namespaces given in opts directly in the
+// default store, so that no Temporal Server needs to be running. Namespaces
+// which already exist are left as they are.
+func registerNamespaces(ctx context.Context, cfg *config.Config, opts Options, report *Report, logger log.Logger) error {
+	if cfg.ClusterMetadata == nil {
+		return errors.New("clusterMetadata must be set to register namespaces")
+	}
+	clusterName := cfg.ClusterMetadata.CurrentClusterName
+
+	dataStoreFactory, _ := persistenceclient.DataStoreFactoryProvider(
+		persistenceclient.ClusterName(clusterName),
+		resolver.NewNoopResolver(),
+		&cfg.Persistence,
+		nil,
+		logger,
+		metrics.NoopMetricsHandler,
+	)
+	factory := persistenceclient.NewFactory(
+		dataStoreFactory,
+		&cfg.Persistence,
+		nil,
+		serialization.NewSerializer(),
+		nil,
+		clusterName,
+		metrics.NoopMetricsHandler,
+		logger,
+		persistence.NoopHealthSignalAggregator,
+	)
+	defer factory.Close()
+
+	metadataManager, err := factory.NewMetadataManager()
+	if err != nil {
+		return fmt.Errorf("unable to create metadata manager: %w", err)
+	}
+	defer metadataManager.Close()
+
+	for _, name := range opts.Namespaces {
+		target := fmt.Sprintf("namespace %q", name)
+
+		ns := sqliteschema.NewNamespaceConfig(clusterName, name, false)
+		ns.Detail.Config.Retention = timestamp.DurationPtr(opts.NamespaceRetention)
+
+		_, err := metadataManager.CreateNamespace(ctx, &persistence.CreateNamespaceRequest{
+			Namespace:         ns.Detail,
+			IsGlobalNamespace: ns.IsGlobal,
+		})
+
+		var alreadyExists *serviceerror.NamespaceAlreadyExists
+		switch {
+		case errors.As(err, &alreadyExists):
+			report.add(target, false, "already registered")
+		case err != nil:
+			return fmt.Errorf("%s: unable to register: %w", target, err)
+		default:
+			report.add(target, true, "registered with a retention of %v", opts.NamespaceRetention)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package setup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"
+	sqltool "go.temporal.io/server/tools/sql"
+)
+
+const (
+	// pingCacheTTL is how long the result of a ping is reused for.
+	pingCacheTTL = 10 * time.Second
+	// pingSQLStmt and pingCassandraCQL are the statements used to check that
+	// a connection is alive.
+	pingSQLStmt      = `SELECT 1`
+	pingCassandraCQL = `SELECT release_version FROM system.local`
+)
+
+// pingDB is a connection to a datastore which can be checked to be alive.
+type pingDB interface {
+	ping(ctx context.Context) error
+	Close()
+}
+
+// Pinger checks that the datastores of a config can be reached. Connections
+// are kept open between pings, and the result of a ping is reused for a few
+// seconds, so that frequent checks, e.g. readiness probes, don't open a new
+// connection each time. SQLite datastores, which are embedded, and
+// Elasticsearch datastores are not checked.
+type Pinger struct {
+	cfg    *config.Config
+	logger log.Logger
+
+	// conns are the open connections, by target. They are only used by the
+	// ping in progress, so they are not guarded by mu.
+	conns map[string]pingDB
+
+	mu sync.Mutex
+	// done is closed when the ping in progress, if any, completes.
+	done    chan struct{}
+	err     error
+	checked time.Time
+}
+
+// NewPinger returns a Pinger for the datastores of the given config.
+func NewPinger(cfg *config.Config, logger log.Logger) *Pinger {
+	return &Pinger{cfg: cfg, logger: logger, conns: make(map[string]pingDB)}
+}
+
+// Ping returns an error if any of the datastores can't be reached. At most
+// one ping is in progress at a time: if ctx is done before it completes, Ping
+// returns ctx.Err() and the ping carries on in the background for the next
+// callers.
+func (p *Pinger) Ping(ctx context.Context) error {
+	p.mu.Lock()
+	if p.done == nil && !p.checked.IsZero() && time.Since(p.checked) < pingCacheTTL {
+		err := p.err
+		p.mu.Unlock()
+		return err
+	}
+	if p.done == nil {
+		p.done = make(chan struct{})
+		go p.pingAll(p.done)
+	}
+	done := p.done
+	p.mu.Unlock()
+
+	select {
+	case <-done:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// pingAll pings every datastore, records the result and closes done.
+func (p *Pinger) pingAll(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), cassandraTimeout)
+	defer cancel()
+
+	err := p.pingStores(ctx)
+
+	p.mu.Lock()
+	p.err = err
+	p.checked = time.Now()
+	p.done = nil
+	p.mu.Unlock()
+	close(done)
+}
+
+func (p *Pinger) pingStores(ctx context.Context) error {
+	for _, s := range storesOf(p.cfg) {
+		target := s.target()
+		db := p.conns[target]
+		if db == nil {
+			var err error
+			switch {
+			case s.cfg.SQL != nil && s.cfg.SQL.PluginName != sqlite.PluginName:
+				sqlCfg := *s.cfg.SQL
+				var conn *sqltool.Connection
+				conn, err = sqltool.NewConnection(&sqlCfg)
+				if err == nil {
+					db = sqlDB{conn}
+				}
+			case s.cfg.Cassandra != nil:
+				db, err = newCassandraDB(*s.cfg.Cassandra, p.logger)
+			default:
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", target, err)
+			}
+			p.conns[target] = db
+		}
+
+		if err := db.ping(ctx); err != nil {
+			// The connection is opened again on the next ping.
+			db.Close()
+			delete(p.conns, target)
+			return fmt.Errorf("%s: %w", target, err)
+		}
+	}
+	return nil
+}
+
+// ping implements pingDB. The SQL tool's connections don't accept a context,
+// so ctx is not used.
+func (db sqlDB) ping(ctx context.Context) error {
+	return db.Exec(pingSQLStmt)
+}
+
+// ping implements pingDB.
+func (db *cassandraDB) ping(ctx context.Context) error {
+	return db.session.Query(pingCassandraCQL).WithContext(ctx).Exec()
+}
@@ -0,0 +1,64 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin/postgresql"
+)
+
+// fakePingDB is a pingDB whose pings wait for release.
+type fakePingDB struct {
+	pings   atomic.Int32
+	release chan error
+	closed  atomic.Bool
+}
+
+func (db *fakePingDB) ping(ctx context.Context) error {
+	db.pings.Add(1)
+	return <-db.release
+}
+
+func (db *fakePingDB) Close() {
+	db.closed.Store(true)
+}
+
+func TestPinger(t *testing.T) {
+	c := qt.New(t)
+
+	cfg := &config.Config{Persistence: config.Persistence{
+		DefaultStore: "default",
+		DataStores: map[string]config.DataStore{
+			"default": {SQL: &config.SQL{PluginName: postgresql.PluginNameV12}},
+		},
+	}}
+	db := &fakePingDB{release: make(chan error)}
+	p := NewPinger(cfg, log.NewNoopLogger())
+	p.conns[`default store "default"`] = db
+
+	// A caller whose context is done doesn't wait for the ping, which carries
+	// on for the next callers instead of a new one being started.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := p.Ping(ctx)
+	c.Assert(err, qt.Equals, context.Canceled)
+
+	result := make(chan error)
+	go func() {
+		result <- p.Ping(context.Background())
+	}()
+	db.release <- errors.New("connection reset")
+	c.Assert(<-result, qt.ErrorMatches, `default store "default": connection reset`)
+	c.Assert(db.pings.Load(), qt.Equals, int32(1))
+	c.Assert(db.closed.Load(), qt.IsTrue)
+
+	// The result is reused until it expires.
+	err = p.Ping(context.Background())
+	c.Assert(err, qt.ErrorMatches, `default store "default": connection reset`)
+	c.Assert(db.pings.Load(), qt.Equals, int32(1))
+}
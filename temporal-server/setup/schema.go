@@ -0,0 +1,100 @@
+package setup
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/urfave/cli"
+
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/tools/common/schema"
+)
+
+// initialVersion is the version the version tables are created with, before
+// the versioned schema updates are applied, as done by the auto-setup scripts
+// of the upstream images.
+const initialVersion = "0.0"
+
+// errNoSchemaVersion is returned by ReadSchemaVersion when the version tables
+// exist but hold no version for the datastore.
+var errNoSchemaVersion = errors.New("no schema version found")
+
+// schemaDB is a schema.DB which can tell whether its version tables exist.
+type schemaDB interface {
+	schema.DB
+	// hasVersionTables reports whether the version tables exist, by querying
+	// the catalog of the datastore.
+	hasVersionTables() (bool, error)
+}
+
+// readSchemaVersion returns the version of the schema held by db, or "" if it
+// has no version tables or no version yet. Any other error, e.g. a timeout, is
+// returned.
+func readSchemaVersion(db schemaDB) (string, error) {
+	exists, err := db.hasVersionTables()
+	if err != nil {
+		return "", fmt.Errorf("unable to list schema version tables: %w", err)
+	}
+	if !exists {
+		return "", nil
+	}
+
+	version, err := db.ReadSchemaVersion()
+	if errors.Is(err, errNoSchemaVersion) || errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return version, err
+}
+
+// updateSchema creates the version tables if db has none, then applies every
+// versioned update of the embedded schema with the given name, e.g.
+// "postgresql/v12/temporal". It reports the version before and after.
+func updateSchema(db schemaDB, schemaName string, target string, report *Report, logger log.Logger) error {
+	oldVersion, err := readSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if oldVersion == "" {
+		// The setup task only creates the version tables, the schema itself is
+		// created by the versioned updates.
+		if err := schema.Setup(schemaContext(map[string]string{
+			schema.CLIOptVersion: initialVersion,
+		}), db, logger); err != nil {
+			return err
+		}
+		report.add(target, true, "created schema version tables")
+		oldVersion = initialVersion
+	}
+
+	if err := schema.Update(schemaContext(map[string]string{
+		schema.CLIOptSchemaName: schemaName,
+	}), db, logger); err != nil {
+		return err
+	}
+
+	newVersion, err := db.ReadSchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if newVersion == oldVersion {
+		report.add(target, false, "schema %s is up to date at version %s", schemaName, newVersion)
+	} else {
+		report.add(target, true, "updated schema %s from version %s to %s", schemaName, oldVersion, newVersion)
+	}
+
+	return nil
+}
+
+// schemaContext returns the command line context which the schema tasks read
+// their options from, as if the given flags had been passed to the schema
+// tools.
+func schemaContext(flags map[string]string) *cli.Context {
+	set := flag.NewFlagSet("setup", flag.ContinueOnError)
+	for name, value := range flags {
+		set.String(name, value, "")
+	}
+	return cli.NewContext(nil, set, nil)
+}
@@ -0,0 +1,123 @@
+package setup
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"go.temporal.io/server/common/log"
+	postgresqlschema "go.temporal.io/server/schema/postgresql/v12"
+)
+
+// fakeDB is a schema.DB which records the statements executed.
+type fakeDB struct {
+	version      string
+	statements   []string
+	versionTable bool
+	// err is returned by every query, e.g. to simulate a timeout.
+	err error
+}
+
+func (db *fakeDB) Exec(stmt string, args ...interface{}) error {
+	db.statements = append(db.statements, stmt)
+	return nil
+}
+
+func (db *fakeDB) DropAllTables() error {
+	return errors.New("unexpected call to DropAllTables")
+}
+
+func (db *fakeDB) CreateSchemaVersionTables() error {
+	db.versionTable = true
+	return nil
+}
+
+func (db *fakeDB) ReadSchemaVersion() (string, error) {
+	if db.err != nil {
+		return "", db.err
+	}
+	if !db.versionTable {
+		return "", errors.New("no schema_version table")
+	}
+	if db.version == "" {
+		return "", errNoSchemaVersion
+	}
+	return db.version, nil
+}
+
+func (db *fakeDB) hasVersionTables() (bool, error) {
+	return db.versionTable, db.err
+}
+
+func (db *fakeDB) UpdateSchemaVersion(newVersion string, minCompatibleVersion string) error {
+	db.version = newVersion
+	return nil
+}
+
+func (db *fakeDB) WriteSchemaUpdateLog(oldVersion string, newVersion string, manifestMD5 string, desc string) error {
+	return nil
+}
+
+func (db *fakeDB) Close() {}
+
+func (db *fakeDB) Type() string {
+	return "sql"
+}
+
+func TestUpdateSchema(t *testing.T) {
+	c := qt.New(t)
+
+	db := &fakeDB{}
+	logger := log.NewNoopLogger()
+	schemaName := "postgresql/v12/temporal"
+
+	// A new database gets the version tables and every update.
+	report := &Report{}
+	err := updateSchema(db, schemaName, "default store", report, logger)
+	c.Assert(err, qt.IsNil)
+	c.Assert(db.version, qt.Equals, postgresqlschema.Version)
+	c.Assert(db.statements, qt.Not(qt.HasLen), 0)
+	c.Assert(report.Steps, qt.DeepEquals, []Step{{
+		Target:  "default store",
+		Result:  "created schema version tables",
+		Changed: true,
+	}, {
+		Target:  "default store",
+		Result:  "updated schema postgresql/v12/temporal from version 0.0 to " + postgresqlschema.Version,
+		Changed: true,
+	}})
+
+	// Running again changes nothing.
+	db.statements = nil
+	report = &Report{}
+	err = updateSchema(db, schemaName, "default store", report, logger)
+	c.Assert(err, qt.IsNil)
+	c.Assert(db.statements, qt.HasLen, 0)
+	c.Assert(report.Changed(), qt.Equals, 0)
+	c.Assert(report.Steps, qt.DeepEquals, []Step{{
+		Target: "default store",
+		Result: "schema postgresql/v12/temporal is up to date at version " + postgresqlschema.Version,
+	}})
+}
+
+func TestUpdateSchemaReadError(t *testing.T) {
+	c := qt.New(t)
+
+	// An error other than missing version tables is returned, without
+	// setting up the version tables again.
+	db := &fakeDB{versionTable: true, version: "1.0", err: errors.New("i/o timeout")}
+	report := &Report{}
+	err := updateSchema(db, "postgresql/v12/temporal", "default store", report, log.NewNoopLogger())
+	c.Assert(err, qt.ErrorMatches, "unable to list schema version tables: i/o timeout")
+	c.Assert(db.version, qt.Equals, "1.0")
+	c.Assert(db.statements, qt.HasLen, 0)
+	c.Assert(report.Steps, qt.HasLen, 0)
+}
+
+func TestUpdateSchemaUnknownSchema(t *testing.T) {
+	c := qt.New(t)
+
+	db := &fakeDB{versionTable: true, version: "1.0"}
+	err := updateSchema(db, "postgresql/v13/temporal", "default store", &Report{}, log.NewNoopLogger())
+	c.Assert(err, qt.ErrorMatches, `.*schema-name\) must be one of.*`)
+}
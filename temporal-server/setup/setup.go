@@ -0,0 +1,196 @@
+// Package setup prepares the datastores of a Temporal Server: it waits for
+// them to be reachable, creates or updates their schema and registers
+// namespaces. Every step is idempotent, so it is safe to run on every start.
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+)
+
+// Options control what Run does.
+type Options struct {
+	// WaitTimeout is how long to wait for each datastore to be reachable.
+	WaitTimeout time.Duration
+	// Namespaces are the namespaces to register.
+	Namespaces []string
+	// NamespaceRetention is the workflow execution retention period of the
+	// namespaces which are registered.
+	NamespaceRetention time.Duration
+	// ReplicationFactor is the replication factor of the Cassandra keyspaces
+	// which are created.
+	ReplicationFactor int
+}
+
+// Step is something that Run did, or found to be already done.
+type Step struct {
+	// Target is what the step concerns, e.g. "default store".
+	Target string
+	// Result describes what was done or found.
+	Result string
+	// Changed is set if the step changed anything.
+	Changed bool
+}
+
+// String implements fmt.Stringer.
+func (s Step) String() string {
+	status := "unchanged"
+	if s.Changed {
+		status = "changed"
+	}
+	return fmt.Sprintf("%-9s %s: %s", status, s.Target, s.Result)
+}
+
+// Report lists the steps taken by Run.
+type Report struct {
+	Steps []Step
+}
+
+// Changed returns the number of steps which changed anything.
+func (r *Report) Changed() int {
+	n := 0
+	for _, s := range r.Steps {
+		if s.Changed {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) add(target string, changed bool, format string, args ...interface{}) {
+	r.Steps = append(r.Steps, Step{
+		Target:  target,
+		Result:  fmt.Sprintf(format, args...),
+		Changed: changed,
+	})
+}
+
+// Run sets up the datastores of the given config and registers the namespaces
+// given in opts. The returned report lists the steps taken so far, even if an
+// error is returned.
+func Run(ctx context.Context, cfg *config.Config, opts Options, logger log.Logger) (*Report, error) {
+	report := &Report{}
+
+	for _, store := range storesOf(cfg) {
+		logger.Info("Setting up datastore", tag.NewStringTag("store", store.name))
+		if err := setupStore(ctx, store, opts, report, logger); err != nil {
+			return report, fmt.Errorf("%s: %w", store.target(), err)
+		}
+	}
+
+	if len(opts.Namespaces) > 0 {
+		if err := registerNamespaces(ctx, cfg, opts, report, logger); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// store is a datastore together with the schema it holds.
+type store struct {
+	// role is either "default" or "visibility".
+	role string
+	name string
+	cfg  config.DataStore
+}
+
+func (s store) target() string {
+	return fmt.Sprintf("%s store %q", s.role, s.name)
+}
+
+// storesOf returns the datastores used by the given config. A datastore
+// holding both the default and visibility schemas is returned twice.
+func storesOf(cfg *config.Config) []store {
+	stores := []store{{
+		role: "default",
+		name: cfg.Persistence.DefaultStore,
+		cfg:  cfg.Persistence.DataStores[cfg.Persistence.DefaultStore],
+	}}
+
+	for _, name := range []string{cfg.Persistence.VisibilityStore, cfg.Persistence.SecondaryVisibilityStore} {
+		if name != "" {
+			stores = append(stores, store{
+				role: "visibility",
+				name: name,
+				cfg:  cfg.Persistence.DataStores[name],
+			})
+		}
+	}
+
+	return stores
+}
+
+// errStoreMissing is wrapped by the errors returned when the database or
+// keyspace of a datastore doesn't exist.
+var errStoreMissing = errors.New("datastore missing")
+
+// schemaName returns the name of the embedded schema held by s, e.g.
+// "postgresql/v12/temporal".
+func (s store) schemaName() (string, error) {
+	name := "temporal"
+	if s.role == "visibility" {
+		name = "visibility"
+	}
+
+	switch {
+	case s.cfg.SQL != nil:
+		dir, ok := sqlSchemaDirs[s.cfg.SQL.PluginName]
+		if !ok {
+			return "", fmt.Errorf("unsupported sql plugin %q", s.cfg.SQL.PluginName)
+		}
+		return dir + "/" + name, nil
+	case s.cfg.Cassandra != nil:
+		return "cassandra/" + name, nil
+	default:
+		return "", errUnsupportedStore
+	}
+}
+
+// elasticsearchSkipped is the result of the steps concerning Elasticsearch
+// datastores.
+const elasticsearchSkipped = "skipped, Elasticsearch indices are not managed by setup"
+
+// errUnsupportedStore is returned for datastores whose schema isn't managed.
+var errUnsupportedStore = errors.New("unsupported datastore, only sql and cassandra are supported")
+
+func setupStore(ctx context.Context, s store, opts Options, report *Report, logger log.Logger) error {
+	switch {
+	case s.cfg.SQL != nil:
+		return setupSQLStore(ctx, s, opts, report, logger)
+	case s.cfg.Cassandra != nil:
+		return setupCassandraStore(ctx, s, opts, report, logger)
+	case s.cfg.Elasticsearch != nil:
+		report.add(s.target(), false, elasticsearchSkipped)
+		return nil
+	default:
+		return errUnsupportedStore
+	}
+}
+
+// waitFor calls connect until it succeeds, the timeout expires or ctx is
+// done.
+func waitFor(ctx context.Context, timeout time.Duration, what string, logger log.Logger, connect func() error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		err := connect()
+		if err == nil {
+			return nil
+		}
+
+		logger.Info("Waiting for datastore", tag.NewStringTag("datastore", what), tag.Error(err))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s not reachable after %v: %w", what, timeout, err)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
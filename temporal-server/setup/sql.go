@@ -0,0 +1,109 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin/mysql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin/postgresql"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"
+	sqltool "go.temporal.io/server/tools/sql"
+)
+
+// sqlSchemaVersionTable is the version table created by the SQL plugins.
+const sqlSchemaVersionTable = "schema_version"
+
+// sqlSchemaDirs are the directories of the embedded schemas of each SQL
+// plugin.
+var sqlSchemaDirs = map[string]string{
+	mysql.PluginName:            "mysql/v57",
+	mysql.PluginNameV8:          "mysql/v8",
+	postgresql.PluginName:       "postgresql/v96",
+	postgresql.PluginNamePGX:    "postgresql/v96",
+	postgresql.PluginNameV12:    "postgresql/v12",
+	postgresql.PluginNameV12PGX: "postgresql/v12",
+}
+
+// sqliteSkipped is the result of the steps concerning SQLite datastores.
+const sqliteSkipped = `skipped, the SQLite schema is set up by the server when the "setup" connect attribute is "true"`
+
+func setupSQLStore(ctx context.Context, s store, opts Options, report *Report, logger log.Logger) error {
+	cfg := *s.cfg.SQL
+
+	if cfg.PluginName == sqlite.PluginName {
+		report.add(s.target(), false, sqliteSkipped)
+		return nil
+	}
+
+	schemaName, err := s.schemaName()
+	if err != nil {
+		return err
+	}
+
+	conn, err := openSQLStore(ctx, s, opts, logger)
+	if errors.Is(err, errStoreMissing) {
+		// DoCreateDatabase connects to the default database by overwriting
+		// the name of the database to create, so it is given a copy.
+		createCfg := cfg
+		if err := sqltool.DoCreateDatabase(&createCfg, ""); err != nil {
+			return fmt.Errorf("unable to create database %q: %w", cfg.DatabaseName, err)
+		}
+		report.add(s.target(), true, "created database %q", cfg.DatabaseName)
+
+		conn, err = sqltool.NewConnection(&cfg)
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return updateSchema(sqlDB{conn}, schemaName, s.target(), report, logger)
+}
+
+// sqlDB implements schemaDB for a SQL database.
+type sqlDB struct {
+	*sqltool.Connection
+}
+
+// hasVersionTables implements schemaDB.
+func (db sqlDB) hasVersionTables() (bool, error) {
+	tables, err := db.ListTables()
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(tables, sqlSchemaVersionTable), nil
+}
+
+// openSQLStore waits for the server of s to be reachable, then connects to
+// its database. If the database can't be connected to, e.g. because it
+// doesn't exist yet, the returned error wraps errStoreMissing.
+func openSQLStore(ctx context.Context, s store, opts Options, logger log.Logger) (*sqltool.Connection, error) {
+	cfg := *s.cfg.SQL
+
+	// The database may not exist yet, so wait for the server by connecting to
+	// its default database.
+	serverCfg := cfg
+	serverCfg.DatabaseName = ""
+	err := waitFor(ctx, opts.WaitTimeout, fmt.Sprintf("%s server %s", cfg.PluginName, cfg.ConnectAddr), logger, func() error {
+		conn, err := sqltool.NewConnection(&serverCfg)
+		if err != nil {
+			return err
+		}
+		conn.Close()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sqltool.NewConnection(&cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: database %q: %v", errStoreMissing, cfg.DatabaseName, err)
+	}
+	return conn, nil
+}
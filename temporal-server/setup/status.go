@@ -0,0 +1,304 @@
+package setup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/blang/semver/v4"
+
+	"go.temporal.io/server/common/config"
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/persistence/sql/sqlplugin/sqlite"
+	dbschemas "go.temporal.io/server/schema"
+	sqltool "go.temporal.io/server/tools/sql"
+)
+
+// SchemaUpdate is a versioned update of an embedded schema.
+type SchemaUpdate struct {
+	Version     string
+	Description string
+}
+
+// SchemaStatus is the state of the schema of a datastore.
+type SchemaStatus struct {
+	// Target is the datastore, e.g. "default store".
+	Target string
+	// SchemaName is the name of the embedded schema held by the datastore,
+	// e.g. "postgresql/v12/temporal". It is empty if the schema of the
+	// datastore isn't managed, in which case Skipped says why.
+	SchemaName string
+	Skipped    string
+	// CurrentVersion is the version of the schema in the datastore. It is
+	// empty if the datastore has no schema yet, or if it wasn't read.
+	CurrentVersion string
+	// LatestVersion is the version of the embedded schema.
+	LatestVersion string
+	// Pending are the updates which are not yet applied.
+	Pending []SchemaUpdate
+	// Missing is set if the database or keyspace of the datastore doesn't
+	// exist yet.
+	Missing bool
+}
+
+// Behind reports whether updates of the embedded schema are not yet applied
+// to the datastore.
+func (s SchemaStatus) Behind() bool {
+	return s.SchemaName != "" && (s.CurrentVersion == "" || len(s.Pending) > 0)
+}
+
+// Ahead reports whether the schema of the datastore is newer than the
+// embedded schema, e.g. because the Temporal Server was downgraded.
+func (s SchemaStatus) Ahead() bool {
+	if s.SchemaName == "" || s.CurrentVersion == "" {
+		return false
+	}
+	current, err := semver.ParseTolerant(s.CurrentVersion)
+	if err != nil {
+		return false
+	}
+	latest, err := semver.ParseTolerant(s.LatestVersion)
+	if err != nil {
+		return false
+	}
+	return current.GT(latest)
+}
+
+// String implements fmt.Stringer.
+func (s SchemaStatus) String() string {
+	var state string
+	switch {
+	case s.SchemaName == "":
+		return fmt.Sprintf("%s: %s", s.Target, s.Skipped)
+	case s.Missing:
+		state = "database missing"
+	case s.CurrentVersion == "":
+		state = "no schema"
+	case s.Ahead():
+		state = "ahead of this server"
+	case len(s.Pending) == 0:
+		state = "up to date"
+	default:
+		state = fmt.Sprintf("%d update(s) pending", len(s.Pending))
+	}
+	current := s.CurrentVersion
+	if current == "" {
+		current = "-"
+	}
+	return fmt.Sprintf("%s: schema %s at version %s of %s, %s", s.Target, s.SchemaName, current, s.LatestVersion, state)
+}
+
+// SchemaVersions returns the schema the datastores of the given config are
+// expected to hold, without connecting to them.
+func SchemaVersions(cfg *config.Config) ([]SchemaStatus, error) {
+	var statuses []SchemaStatus
+	for _, store := range storesOf(cfg) {
+		status, err := expectedSchema(store)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", store.target(), err)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Status connects to the datastores of the given config and returns the state
+// of their schema.
+func Status(ctx context.Context, cfg *config.Config, opts Options, logger log.Logger) ([]SchemaStatus, error) {
+	var statuses []SchemaStatus
+	for _, store := range storesOf(cfg) {
+		status, err := storeStatus(ctx, store, opts, logger)
+		if err != nil {
+			return statuses, fmt.Errorf("%s: %w", store.target(), err)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Migrate creates or updates the schema of the datastores of the given config
+// to the latest version, as Run does but without registering namespaces. If
+// dryRun is set, the datastores are left unchanged and the returned report
+// lists what would be done.
+func Migrate(ctx context.Context, cfg *config.Config, opts Options, dryRun bool, logger log.Logger) (*Report, error) {
+	if !dryRun {
+		opts.Namespaces = nil
+		return Run(ctx, cfg, opts, logger)
+	}
+
+	report := &Report{}
+	statuses, err := Status(ctx, cfg, opts, logger)
+	for _, status := range statuses {
+		planMigration(status, report)
+	}
+	return report, err
+}
+
+// planMigration adds the steps Migrate would take for a datastore in the
+// given state to report.
+func planMigration(status SchemaStatus, report *Report) {
+	switch {
+	case status.SchemaName == "":
+		report.add(status.Target, false, status.Skipped)
+		return
+	case status.Missing:
+		report.add(status.Target, true, "would create the database")
+		fallthrough
+	case status.CurrentVersion == "":
+		report.add(status.Target, true, "would create schema version tables")
+	}
+
+	if len(status.Pending) == 0 {
+		report.add(status.Target, false, "schema %s is up to date at version %s", status.SchemaName, status.CurrentVersion)
+		return
+	}
+	for _, update := range status.Pending {
+		report.add(status.Target, true, "would update schema %s to version %s: %s", status.SchemaName, update.Version, update.Description)
+	}
+}
+
+// expectedSchema returns the status of s as if it held no schema.
+func expectedSchema(s store) (SchemaStatus, error) {
+	status := SchemaStatus{Target: s.target()}
+
+	switch {
+	case s.cfg.SQL != nil && s.cfg.SQL.PluginName == sqlite.PluginName:
+		status.Skipped = sqliteSkipped
+		return status, nil
+	case s.cfg.Elasticsearch != nil:
+		status.Skipped = elasticsearchSkipped
+		return status, nil
+	}
+
+	schemaName, err := s.schemaName()
+	if err != nil {
+		return status, err
+	}
+	status.SchemaName = schemaName
+
+	status.Pending, err = pendingUpdates(dbschemas.Assets(), schemaName, initialVersion)
+	if err != nil {
+		return status, err
+	}
+	status.LatestVersion = latestVersion(status.Pending, initialVersion)
+
+	return status, nil
+}
+
+func storeStatus(ctx context.Context, s store, opts Options, logger log.Logger) (SchemaStatus, error) {
+	status, err := expectedSchema(s)
+	if err != nil || status.SchemaName == "" {
+		return status, err
+	}
+
+	var db schemaDB
+	switch {
+	case s.cfg.SQL != nil:
+		var conn *sqltool.Connection
+		conn, err = openSQLStore(ctx, s, opts, logger)
+		if err == nil {
+			db = sqlDB{conn}
+		}
+	case s.cfg.Cassandra != nil:
+		db, err = openCassandraStore(ctx, s, opts, logger)
+	}
+	if errors.Is(err, errStoreMissing) {
+		status.Missing = true
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+	defer db.Close()
+
+	version, err := readSchemaVersion(db)
+	if err != nil {
+		return status, err
+	}
+	// Without a version, the datastore has no schema yet.
+	if version != "" {
+		status.CurrentVersion = version
+		status.Pending, err = pendingUpdates(dbschemas.Assets(), status.SchemaName, version)
+		if err != nil {
+			return status, err
+		}
+	}
+
+	return status, nil
+}
+
+// manifest is the part of the manifest of a versioned schema update read by
+// pendingUpdates.
+type manifest struct {
+	CurrVersion string
+	Description string
+}
+
+// pendingUpdates returns the versioned updates of the schema with the given
+// name in fsys which are newer than current, in the order they are applied.
+func pendingUpdates(fsys fs.FS, schemaName string, current string) ([]SchemaUpdate, error) {
+	currentVersion, err := semver.ParseTolerant(current)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema version %q: %w", current, err)
+	}
+
+	dir := path.Join(schemaName, "versioned")
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("unknown schema %s: %w", schemaName, err)
+	}
+
+	type update struct {
+		SchemaUpdate
+		version semver.Version
+	}
+	var updates []update
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "v") {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name(), "manifest.json"))
+		if err != nil {
+			return nil, err
+		}
+		var m manifest
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("invalid manifest of %s version %s: %w", schemaName, entry.Name(), err)
+		}
+		version, err := semver.ParseTolerant(m.CurrVersion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest of %s version %s: %w", schemaName, entry.Name(), err)
+		}
+
+		if version.GT(currentVersion) {
+			updates = append(updates, update{
+				SchemaUpdate: SchemaUpdate{Version: m.CurrVersion, Description: m.Description},
+				version:      version,
+			})
+		}
+	}
+
+	slices.SortFunc(updates, func(a, b update) int {
+		return a.version.Compare(b.version)
+	})
+
+	pending := make([]SchemaUpdate, len(updates))
+	for i, u := range updates {
+		pending[i] = u.SchemaUpdate
+	}
+	return pending, nil
+}
+
+// latestVersion returns the version a schema at version current is at once
+// the pending updates are applied.
+func latestVersion(pending []SchemaUpdate, current string) string {
+	if len(pending) == 0 {
+		return current
+	}
+	return pending[len(pending)-1].Version
+}
@@ -0,0 +1,158 @@
+package setup
+
+import (
+	"testing"
+	"testing/fstest"
+
+	qt "github.com/frankban/quicktest"
+	dbschemas "go.temporal.io/server/schema"
+	postgresqlschema "go.temporal.io/server/schema/postgresql/v12"
+)
+
+var testSchemas = fstest.MapFS{
+	"test/temporal/versioned/v1.0/manifest.json":  {Data: []byte(`{"CurrVersion": "1.0", "Description": "base version"}`)},
+	"test/temporal/versioned/v1.2/manifest.json":  {Data: []byte(`{"CurrVersion": "1.2", "Description": "add queues"}`)},
+	"test/temporal/versioned/v1.10/manifest.json": {Data: []byte(`{"CurrVersion": "1.10", "Description": "add index"}`)},
+	"test/temporal/schema.sql":                    {Data: []byte(`CREATE TABLE t (id INT);`)},
+}
+
+func TestPendingUpdates(t *testing.T) {
+	c := qt.New(t)
+
+	pending, err := pendingUpdates(testSchemas, "test/temporal", "0.0")
+	c.Assert(err, qt.IsNil)
+	c.Assert(pending, qt.DeepEquals, []SchemaUpdate{
+		{Version: "1.0", Description: "base version"},
+		{Version: "1.2", Description: "add queues"},
+		{Version: "1.10", Description: "add index"},
+	})
+
+	pending, err = pendingUpdates(testSchemas, "test/temporal", "1.2")
+	c.Assert(err, qt.IsNil)
+	c.Assert(pending, qt.DeepEquals, []SchemaUpdate{
+		{Version: "1.10", Description: "add index"},
+	})
+
+	pending, err = pendingUpdates(testSchemas, "test/temporal", "1.10")
+	c.Assert(err, qt.IsNil)
+	c.Assert(pending, qt.HasLen, 0)
+
+	_, err = pendingUpdates(testSchemas, "test/visibility", "0.0")
+	c.Assert(err, qt.ErrorMatches, `unknown schema test/visibility: .*`)
+}
+
+func TestPendingUpdatesEmbedded(t *testing.T) {
+	c := qt.New(t)
+
+	pending, err := pendingUpdates(dbschemas.Assets(), "postgresql/v12/temporal", initialVersion)
+	c.Assert(err, qt.IsNil)
+	c.Assert(latestVersion(pending, initialVersion), qt.Equals, postgresqlschema.Version)
+}
+
+func TestPlanMigration(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about  string
+		status SchemaStatus
+		expect []Step
+	}{{
+		about: "skipped store",
+		status: SchemaStatus{
+			Target:  "default store",
+			Skipped: sqliteSkipped,
+		},
+		expect: []Step{{Target: "default store", Result: sqliteSkipped}},
+	}, {
+		about: "missing database",
+		status: SchemaStatus{
+			Target:     "default store",
+			SchemaName: "test/temporal",
+			Missing:    true,
+			Pending:    []SchemaUpdate{{Version: "1.0", Description: "base version"}},
+		},
+		expect: []Step{
+			{Target: "default store", Result: "would create the database", Changed: true},
+			{Target: "default store", Result: "would create schema version tables", Changed: true},
+			{Target: "default store", Result: "would update schema test/temporal to version 1.0: base version", Changed: true},
+		},
+	}, {
+		about: "pending updates",
+		status: SchemaStatus{
+			Target:         "visibility store",
+			SchemaName:     "test/temporal",
+			CurrentVersion: "1.0",
+			Pending:        []SchemaUpdate{{Version: "1.2", Description: "add queues"}},
+		},
+		expect: []Step{
+			{Target: "visibility store", Result: "would update schema test/temporal to version 1.2: add queues", Changed: true},
+		},
+	}, {
+		about: "up to date",
+		status: SchemaStatus{
+			Target:         "default store",
+			SchemaName:     "test/temporal",
+			CurrentVersion: "1.10",
+		},
+		expect: []Step{
+			{Target: "default store", Result: "schema test/temporal is up to date at version 1.10"},
+		},
+	}}
+
+	for _, test := range tests {
+		c.Run(test.about, func(c *qt.C) {
+			report := &Report{}
+			planMigration(test.status, report)
+			c.Assert(report.Steps, qt.DeepEquals, test.expect)
+		})
+	}
+}
+
+func TestSchemaStatusDrift(t *testing.T) {
+	c := qt.New(t)
+
+	tests := []struct {
+		about  string
+		status SchemaStatus
+		behind bool
+		ahead  bool
+		expect string
+	}{{
+		about:  "skipped store",
+		status: SchemaStatus{Target: "visibility store", Skipped: elasticsearchSkipped},
+		expect: "visibility store: " + elasticsearchSkipped,
+	}, {
+		about:  "missing database",
+		status: SchemaStatus{Target: "default store", SchemaName: "test/temporal", LatestVersion: "1.10", Missing: true},
+		behind: true,
+		expect: "default store: schema test/temporal at version - of 1.10, database missing",
+	}, {
+		about: "pending updates",
+		status: SchemaStatus{
+			Target:         "default store",
+			SchemaName:     "test/temporal",
+			CurrentVersion: "1.2",
+			LatestVersion:  "1.10",
+			Pending:        []SchemaUpdate{{Version: "1.10", Description: "add index"}},
+		},
+		behind: true,
+		expect: "default store: schema test/temporal at version 1.2 of 1.10, 1 update(s) pending",
+	}, {
+		about:  "up to date",
+		status: SchemaStatus{Target: "default store", SchemaName: "test/temporal", CurrentVersion: "1.10", LatestVersion: "1.10"},
+		expect: "default store: schema test/temporal at version 1.10 of 1.10, up to date",
+	}, {
+		about:  "ahead",
+		status: SchemaStatus{Target: "default store", SchemaName: "test/temporal", CurrentVersion: "1.11", LatestVersion: "1.10"},
+		ahead:  true,
+		expect: "default store: schema test/temporal at version 1.11 of 1.10, ahead of this server",
+	}}
+
+	for _, test := range tests {
+		c.Run(test.about, func(c *qt.C) {
+			c.Assert(test.status.Behind(), qt.Equals, test.behind)
+			c.Assert(test.status.Ahead(), qt.Equals, test.ahead)
+			c.Assert(test.status.String(), qt.Equals, test.expect)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"go.temporal.io/server/common/log"
+	"go.temporal.io/server/common/log/tag"
+	"go.temporal.io/server/common/metrics"
+	"go.temporal.io/server/common/primitives"
+	"go.temporal.io/server/temporal"
+)
+
+// stopOrder is the order in which services are stopped: the frontend first so
+// that no new requests are accepted, then the services handling them.
+var stopOrder = []string{
+	string(primitives.FrontendService),
+	string(primitives.WorkerService),
+	string(primitives.InternalFrontendService),
+	string(primitives.MatchingService),
+	string(primitives.HistoryService),
+}
+
+// serviceServer is a Temporal server running a single service. Temporal stops
+// all the services of a server at once, so each service gets its own server to
+// be stopped in stopOrder.
+type serviceServer struct {
+	service string
+	server  temporal.Server
+}
+
+// sharedMetricsHandler is a metrics.Handler shared by the servers of each
+// service, which stop it when they are stopped. It is only stopped once every
+// server is, by calling stop.
+type sharedMetricsHandler struct {
+	metrics.Handler
+}
+
+// Stop implements metrics.Handler. It does nothing.
+func (sharedMetricsHandler) Stop(log.Logger) {}
+
+func (h sharedMetricsHandler) stop(logger log.Logger) {
+	h.Handler.Stop(logger)
+}
+
+// newServers creates a server for each of the given services, with the given
+// options and metrics handler.
+func newServers(services []string, metricsHandler sharedMetricsHandler, opts ...temporal.ServerOption) ([]serviceServer, error) {
+	var servers []serviceServer
+	for _, service := range services {
+		server, err := temporal.NewServer(append(opts,
+			temporal.ForServices([]string{service}),
+			temporal.WithCustomMetricsHandler(metricsHandler),
+		)...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", service, err)
+		}
+		servers = append(servers, serviceServer{service: service, server: server})
+	}
+	return servers, nil
+}
+
+// startServers starts the given servers concurrently, as Temporal does for the
+// services of a server.
+func startServers(servers []serviceServer) error {
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func(i int, s serviceServer) {
+			defer wg.Done()
+			if err := s.server.Start(); err != nil {
+				errs[i] = fmt.Errorf("failed to start service %s: %w", s.service, err)
+			}
+		}(i, s)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// stopServers stops the given servers one by one in stopOrder, logging how
+// long each service took to stop.
+func stopServers(servers []serviceServer, logger log.Logger) {
+	servers = slices.Clone(servers)
+	slices.SortStableFunc(servers, func(a, b serviceServer) int {
+		return stopRank(a.service) - stopRank(b.service)
+	})
+
+	for _, s := range servers {
+		logger.Info("Stopping service", tag.Service(primitives.ServiceName(s.service)))
+		start := time.Now()
+		if err := s.server.Stop(); err != nil {
+			logger.Error("Failed to stop service", tag.Service(primitives.ServiceName(s.service)), tag.Error(err))
+		}
+		logger.Info("Stopped service",
+			tag.Service(primitives.ServiceName(s.service)),
+			tag.NewDurationTag("duration", time.Since(start)),
+		)
+	}
+}
+
+// stopRank returns the position of service in stopOrder. Unknown services
+// are stopped last.
+func stopRank(service string) int {
+	if i := slices.Index(stopOrder, service); i >= 0 {
+		return i
+	}
+	return len(stopOrder)
+}
+
+// waitForShutdown waits for the first value of interruptCh, then calls
+// markNotReady, waits for drainPeriod so that load balancers stop sending
+// requests, and stops the servers. A second interrupt during the drain period
+// skips the rest of it.
+func waitForShutdown(interruptCh <-chan os.Signal, drainPeriod time.Duration, markNotReady func(), servers []serviceServer, logger log.Logger) {
+	sig := <-interruptCh
+	logger.Info("Received interrupt signal, stopping the server.", tag.Value(sig), tag.NewDurationTag("drain-period", drainPeriod))
+	markNotReady()
+
+	if drainPeriod > 0 {
+		select {
+		case <-time.After(drainPeriod):
+		case sig := <-interruptCh:
+			logger.Info("Received second interrupt signal, skipping the drain period.", tag.Value(sig))
+		}
+	}
+
+	stopServers(servers, logger)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/urfave/cli/v2"
+	"go.temporal.io/server/common/log"
+)
+
+type fakeServer struct {
+	service string
+	stopped *[]string
+}
+
+func (s fakeServer) Start() error {
+	return nil
+}
+
+func (s fakeServer) Stop() error {
+	*s.stopped = append(*s.stopped, s.service)
+	return nil
+}
+
+func TestWaitForShutdown(t *testing.T) {
+	c := qt.New(t)
+
+	var stopped []string
+	var servers []serviceServer
+	for _, service := range []string{"history", "matching", "frontend", "worker"} {
+		servers = append(servers, serviceServer{
+			service: service,
+			server:  fakeServer{service: service, stopped: &stopped},
+		})
+	}
+
+	interruptCh := make(chan os.Signal, 2)
+	interruptCh <- syscall.SIGTERM
+	interruptCh <- syscall.SIGTERM
+
+	notReady := false
+	start := time.Now()
+	waitForShutdown(interruptCh, time.Hour, func() { notReady = true }, servers, log.NewNoopLogger())
+
+	c.Assert(notReady, qt.IsTrue)
+	// The second signal skips the drain period.
+	c.Assert(time.Since(start) < time.Hour, qt.IsTrue)
+	c.Assert(stopped, qt.DeepEquals, []string{"frontend", "worker", "matching", "history"})
+}
+
+func TestStartDevShutdown(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts every service of the server")
+	}
+	c := qt.New(t)
+
+	root := t.TempDir()
+	logFile := filepath.Join(root, "temporal.log")
+	c.Assert(os.Mkdir(filepath.Join(root, "config"), 0o755), qt.IsNil)
+	// Only the log section is used in development mode, the persistence
+	// section is required to load the config.
+	config := fmt.Sprintf("log:\n  level: info\n  outputFile: %s\npersistence:\n  defaultStore: sqlite\n  numHistoryShards: 1\n", logFile)
+	c.Assert(os.WriteFile(filepath.Join(root, "config", "development.yaml"), []byte(config), 0o644), qt.IsNil)
+
+	app := buildCLI()
+	// The start command exits through cli.Exit, which must not exit the test.
+	app.ExitErrHandler = func(*cli.Context, error) {}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run([]string{"temporal", "--root", root, "start", "--dev", "--drain-period", "10ms"})
+	}()
+
+	// The signal handler is set up before the servers are started, so SIGTERM
+	// can be sent as soon as they are.
+	deadline := time.Now().Add(time.Minute)
+	for !slices.Contains(readLogMessages(c, logFile), "Starting server for services") {
+		select {
+		case err := <-done:
+			c.Fatalf("server exited before starting: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			c.Fatal("server did not start")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	c.Assert(syscall.Kill(os.Getpid(), syscall.SIGTERM), qt.IsNil)
+
+	select {
+	case err := <-done:
+		c.Assert(err, qt.ErrorMatches, "All services are stopped.")
+	case <-time.After(time.Minute):
+		c.Fatal("server did not stop")
+	}
+
+	// Without http.listenAddress, the drain period can't flip the readiness.
+	c.Assert(readLogMessages(c, logFile), qt.Any(qt.Matches), "--drain-period is set but http.listenAddress is not.*")
+
+	var stopped []string
+	for _, entry := range readLogEntries(c, logFile) {
+		if entry.Msg == "Stopping service" {
+			stopped = append(stopped, entry.Service)
+		}
+	}
+	c.Assert(stopped, qt.DeepEquals, []string{"frontend", "worker", "matching", "history"})
+}
+
+// logEntry is a line of the server's JSON log.
+type logEntry struct {
+	Msg     string `json:"msg"`
+	Service string `json:"service"`
+}
+
+// readLogEntries returns the entries written so far to the given log file.
+func readLogEntries(c *qt.C, logFile string) []logEntry {
+	data, err := os.ReadFile(logFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	c.Assert(err, qt.IsNil)
+
+	var entries []logEntry
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var entry logEntry
+		if json.Unmarshal(line, &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// readLogMessages returns the messages written so far to the given log file.
+func readLogMessages(c *qt.C, logFile string) []string {
+	var messages []string
+	for _, entry := range readLogEntries(c, logFile) {
+		messages = append(messages, entry.Msg)
+	}
+	return messages
+}